@@ -5,7 +5,9 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"runtime"
 	"testing"
+	"testing/fstest"
 	"time"
 )
 
@@ -32,7 +34,9 @@ func BenchmarkLegacyModePerformance(b *testing.B) {
 			if err != nil {
 				b.Fatalf("Failed to create engine: %v", err)
 			}
-			engine.Init()
+			if err := engine.Init(); err != nil {
+				b.Fatalf("Failed to initialize engine: %v", err)
+			}
 			engine.Close()
 		}
 	})
@@ -42,7 +46,9 @@ func BenchmarkLegacyModePerformance(b *testing.B) {
 	if err != nil {
 		b.Fatalf("Failed to create engine: %v", err)
 	}
-	engine.Init()
+	if err := engine.Init(); err != nil {
+		b.Fatalf("Failed to initialize engine: %v", err)
+	}
 	defer engine.Close()
 
 	b.Run("PageRendering", func(b *testing.B) {
@@ -129,7 +135,9 @@ func BenchmarkMultiThemeModePerformance(b *testing.B) {
 			if err != nil {
 				b.Fatalf("Failed to create engine: %v", err)
 			}
-			engine.Init()
+			if err := engine.Init(); err != nil {
+				b.Fatalf("Failed to initialize engine: %v", err)
+			}
 			engine.Close()
 		}
 	})
@@ -139,7 +147,9 @@ func BenchmarkMultiThemeModePerformance(b *testing.B) {
 	if err != nil {
 		b.Fatalf("Failed to create engine: %v", err)
 	}
-	engine.Init()
+	if err := engine.Init(); err != nil {
+		b.Fatalf("Failed to initialize engine: %v", err)
+	}
 	defer engine.Close()
 
 	b.Run("PageRendering", func(b *testing.B) {
@@ -237,14 +247,18 @@ func BenchmarkPerformanceComparison(b *testing.B) {
 	if err != nil {
 		b.Fatalf("Failed to create legacy engine: %v", err)
 	}
-	legacyEngine.Init()
+	if err := legacyEngine.Init(); err != nil {
+		b.Fatalf("Failed to initialize engine: %v", err)
+	}
 	defer legacyEngine.Close()
 
 	multiEngine, err := NewEngine(multiDir, DefaultLoadTemplate, funcMap, EnableMultiTheme(true))
 	if err != nil {
 		b.Fatalf("Failed to create multi-theme engine: %v", err)
 	}
-	multiEngine.Init()
+	if err := multiEngine.Init(); err != nil {
+		b.Fatalf("Failed to initialize engine: %v", err)
+	}
 	defer multiEngine.Close()
 
 	data := H{
@@ -333,7 +347,9 @@ func BenchmarkMemoryUsage(b *testing.B) {
 			if err != nil {
 				b.Fatalf("Failed to create engine: %v", err)
 			}
-			engine.Init()
+			if err := engine.Init(); err != nil {
+				b.Fatalf("Failed to initialize engine: %v", err)
+			}
 
 			// 执行一些操作来加载模板
 			var buf bytes.Buffer
@@ -353,7 +369,9 @@ func BenchmarkMemoryUsage(b *testing.B) {
 			if err != nil {
 				b.Fatalf("Failed to create engine: %v", err)
 			}
-			engine.Init()
+			if err := engine.Init(); err != nil {
+				b.Fatalf("Failed to initialize engine: %v", err)
+			}
 
 			// 执行一些操作来加载模板
 			var buf bytes.Buffer
@@ -369,7 +387,9 @@ func BenchmarkMemoryUsage(b *testing.B) {
 		if err != nil {
 			b.Fatalf("Failed to create engine: %v", err)
 		}
-		engine.Init()
+		if err := engine.Init(); err != nil {
+			b.Fatalf("Failed to initialize engine: %v", err)
+		}
 		defer engine.Close()
 
 		themes := append(smallThemes, largeThemes...)
@@ -413,7 +433,9 @@ func BenchmarkConcurrentAccess(b *testing.B) {
 	if err != nil {
 		b.Fatalf("Failed to create engine: %v", err)
 	}
-	engine.Init()
+	if err := engine.Init(); err != nil {
+		b.Fatalf("Failed to initialize engine: %v", err)
+	}
 	defer engine.Close()
 
 	data := H{
@@ -533,7 +555,9 @@ func BenchmarkRealWorldScenarios(b *testing.B) {
 	if err != nil {
 		b.Fatalf("Failed to create engine: %v", err)
 	}
-	engine.Init()
+	if err := engine.Init(); err != nil {
+		b.Fatalf("Failed to initialize engine: %v", err)
+	}
 	defer engine.Close()
 
 	b.Run("WebsiteSimulation", func(b *testing.B) {
@@ -665,7 +689,9 @@ func BenchmarkStartupTime(b *testing.B) {
 				b.Fatalf("Failed to create engine: %v", err)
 			}
 
-			engine.Init()
+			if err := engine.Init(); err != nil {
+				b.Fatalf("Failed to initialize engine: %v", err)
+			}
 
 			// 记录启动时间
 			elapsed := time.Since(start)
@@ -695,7 +721,9 @@ func BenchmarkStartupTime(b *testing.B) {
 				b.Fatalf("Failed to create engine: %v", err)
 			}
 
-			engine.Init()
+			if err := engine.Init(); err != nil {
+				b.Fatalf("Failed to initialize engine: %v", err)
+			}
 
 			// 记录启动时间
 			elapsed := time.Since(start)
@@ -725,7 +753,9 @@ func BenchmarkStartupTime(b *testing.B) {
 				b.Fatalf("Failed to create engine: %v", err)
 			}
 
-			engine.Init()
+			if err := engine.Init(); err != nil {
+				b.Fatalf("Failed to initialize engine: %v", err)
+			}
 
 			// 记录启动时间
 			elapsed := time.Since(start)
@@ -735,3 +765,128 @@ func BenchmarkStartupTime(b *testing.B) {
 		}
 	})
 }
+
+// BenchmarkMinify HTML 压缩基准测试
+func BenchmarkMinify(b *testing.B) {
+	// 构造一个包含缩进、注释、内联样式和脚本的典型页面
+	var page bytes.Buffer
+	page.WriteString("<!DOCTYPE html>\n<html>\n  <head>\n    <title>Benchmark</title>\n    <style>\n      /* 页面样式 */\n      body {\n        margin: 0;\n        font-family: sans-serif;\n      }\n    </style>\n  </head>\n  <body>\n")
+	for i := 0; i < 100; i++ {
+		fmt.Fprintf(&page, "    <!-- item %d -->\n    <div class=\"item\">\n      <h2>Item %d</h2>\n      <p>Some <b>bold</b> text   with   spaces.</p>\n    </div>\n", i, i)
+	}
+	page.WriteString("    <pre>\n  keep   this\n    </pre>\n    <script>\n      // 统计脚本\n      var items = document.querySelectorAll('.item')\n      console.log(items.length)\n    </script>\n  </body>\n</html>\n")
+	src := page.Bytes()
+
+	b.Run("MinifyHTML", func(b *testing.B) {
+		b.SetBytes(int64(len(src)))
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			MinifyHTML(src)
+		}
+	})
+
+	// 对比启用压缩前后的页面渲染
+	tempDir, err := os.MkdirTemp("", "benchmark_minify_*")
+	if err != nil {
+		b.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	if err := createLegacyStructure(tempDir); err != nil {
+		b.Fatalf("Failed to create legacy structure: %v", err)
+	}
+
+	engine, err := NewEngine(tempDir, DefaultLoadTemplate, NewFuncMap())
+	if err != nil {
+		b.Fatalf("Failed to create engine: %v", err)
+	}
+	if err := engine.Init(); err != nil {
+		b.Fatalf("Failed to init engine: %v", err)
+	}
+	defer engine.Close()
+
+	data := H{
+		"title":   "Benchmark Test",
+		"content": "This is benchmark content",
+	}
+
+	for _, minify := range []bool{false, true} {
+		b.Run(fmt.Sprintf("PageRendering_Minify_%t", minify), func(b *testing.B) {
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				var buf bytes.Buffer
+				if err := engine.RenderPage(&buf, "sample", data, Minify(minify)); err != nil {
+					b.Fatalf("Failed to render page: %v", err)
+				}
+			}
+		})
+	}
+}
+
+// largeThemeFS 返回包含 pages 个页面、3 个布局和 30 个局部页面的内存主题
+func largeThemeFS(pages int) fstest.MapFS {
+	fsys := fstest.MapFS{}
+	for _, layout := range []string{"layout", "single", "admin"} {
+		fsys["layouts/"+layout+".tmpl"] = &fstest.MapFile{Data: []byte(
+			`<html><head>{{ template "header" . }}</head><body>{{ template "nav" . }}{{ template "content" . }}{{ template "footer" . }}</body></html>`)}
+	}
+	for i := 0; i < 30; i++ {
+		fsys[fmt.Sprintf("partials/widgets/w%02d.tmpl", i)] = &fstest.MapFile{Data: []byte(fmt.Sprintf(
+			`{{ define "widget%02d" }}<div class="w">{{ range .items }}<span title="{{ . }}">{{ . }}</span>{{ end }}</div>{{ end }}`, i))}
+	}
+	fsys["partials/nav.tmpl"] = &fstest.MapFile{Data: []byte(`{{ define "nav" }}<nav>{{ .title }}</nav>{{ end }}`)}
+	fsys["partials/footer.tmpl"] = &fstest.MapFile{Data: []byte(`{{ define "footer" }}<footer>{{ .title }}</footer>{{ end }}`)}
+	for i := 0; i < pages; i++ {
+		fsys[fmt.Sprintf("pages/section%02d/page%03d/page.tmpl", i%10, i)] = &fstest.MapFile{Data: []byte(fmt.Sprintf(
+			`{{ define "header" }}<title>{{ .title }} %d</title>{{ end }}{{ define "content" }}<h1>{{ .title }}</h1>{{ template "widget%02d" . }}{{ end }}`, i, i%30))}
+	}
+	fsys["singles/about.tmpl"] = &fstest.MapFile{Data: []byte(`<p>{{ template "nav" . }}</p>`)}
+	fsys["errors/404.tmpl"] = &fstest.MapFile{Data: []byte(`<p>404</p>`)}
+	return fsys
+}
+
+// BenchmarkLoadLargeTheme 基准测试加载包含数百个页面的主题的耗时、分配和加载后占用的内存
+func BenchmarkLoadLargeTheme(b *testing.B) {
+	for _, pages := range []int{100, 500} {
+		fsys := largeThemeFS(pages)
+
+		b.Run(fmt.Sprintf("Pages%d", pages), func(b *testing.B) {
+			b.ReportAllocs()
+			var render Render
+			for i := 0; i < b.N; i++ {
+				var err error
+				if render, err = LoadTemplateFS(fsys, ".", nil); err != nil {
+					b.Fatalf("Failed to load templates: %v", err)
+				}
+			}
+			b.StopTimer()
+
+			// 加载完成后模板集合占用的堆内存
+			var before, after runtime.MemStats
+			render = nil
+			runtime.GC()
+			runtime.ReadMemStats(&before)
+			render, _ = LoadTemplateFS(fsys, ".", nil)
+			runtime.GC()
+			runtime.ReadMemStats(&after)
+			b.ReportMetric(float64(after.HeapAlloc)-float64(before.HeapAlloc), "heap-B/render")
+			b.ReportMetric(float64(len(render)), "sets")
+		})
+
+		b.Run(fmt.Sprintf("Pages%d_EngineInit", pages), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				engine, err := NewEngineWithFS(fsys, DefaultLoadTemplateFS, nil)
+				if err != nil {
+					b.Fatalf("Failed to create engine: %v", err)
+				}
+				if err := engine.Init(); err != nil {
+					b.Fatalf("Failed to init engine: %v", err)
+				}
+				engine.Close()
+			}
+		})
+	}
+}
@@ -0,0 +1,177 @@
+package template
+
+import (
+	"bytes"
+	"container/list"
+	"sync"
+	"time"
+)
+
+// outputCache 渲染输出缓存，按最近使用淘汰，支持过期时间和标签失效
+type outputCache struct {
+	mu       sync.Mutex
+	capacity int                            // 最多缓存的条目数
+	gen      uint64                         // 每次清空时递增，丢弃清空前开始的渲染结果
+	ll       *list.List                     // 最近使用的条目在前
+	items    map[string]*list.Element       // 缓存键到条目
+	tags     map[string]map[string]struct{} // 标签到缓存键
+	now      func() time.Time
+}
+
+// cacheEntry 缓存条目
+type cacheEntry struct {
+	key     string
+	output  []byte
+	expires time.Time // 为零值时不过期
+	tags    []string
+}
+
+// newOutputCache 创建输出缓存，capacity 不大于 0 时不缓存
+func newOutputCache(capacity int) *outputCache {
+	return &outputCache{
+		capacity: capacity,
+		ll:       list.New(),
+		items:    map[string]*list.Element{},
+		tags:     map[string]map[string]struct{}{},
+		now:      time.Now,
+	}
+}
+
+// get 获取未过期的缓存，同时返回当前的代数，写入缓存时需要传回
+func (c *outputCache) get(key string) (output []byte, gen uint64, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.items[key]
+	if !ok {
+		return nil, c.gen, false
+	}
+	entry := elem.Value.(*cacheEntry)
+	if !entry.expires.IsZero() && !c.now().Before(entry.expires) {
+		c.remove(elem)
+		return nil, c.gen, false
+	}
+	c.ll.MoveToFront(elem)
+	return entry.output, c.gen, true
+}
+
+// set 写入缓存，gen 与当前代数不同（期间缓存被清空过）时丢弃
+func (c *outputCache) set(gen uint64, key string, output []byte, ttl time.Duration, tags []string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.capacity <= 0 || gen != c.gen {
+		return
+	}
+	if elem, ok := c.items[key]; ok {
+		c.remove(elem)
+	}
+
+	entry := &cacheEntry{key: key, output: output, tags: tags}
+	if ttl > 0 {
+		entry.expires = c.now().Add(ttl)
+	}
+	c.items[key] = c.ll.PushFront(entry)
+	for _, tag := range tags {
+		keys, ok := c.tags[tag]
+		if !ok {
+			keys = map[string]struct{}{}
+			c.tags[tag] = keys
+		}
+		keys[key] = struct{}{}
+	}
+
+	for c.ll.Len() > c.capacity {
+		c.remove(c.ll.Back())
+	}
+}
+
+// invalidate 删除带有任一指定标签的缓存，返回删除的条目数
+func (c *outputCache) invalidate(tags ...string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	removed := 0
+	for _, tag := range tags {
+		for key := range c.tags[tag] {
+			if elem, ok := c.items[key]; ok {
+				c.remove(elem)
+				removed++
+			}
+		}
+	}
+	return removed
+}
+
+// purge 清空缓存
+func (c *outputCache) purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.gen++
+	c.ll.Init()
+	c.items = map[string]*list.Element{}
+	c.tags = map[string]map[string]struct{}{}
+}
+
+// len 返回缓存的条目数
+func (c *outputCache) len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.ll.Len()
+}
+
+// remove 删除条目，调用方需持有锁
+func (c *outputCache) remove(elem *list.Element) {
+	entry := c.ll.Remove(elem).(*cacheEntry)
+	delete(c.items, entry.key)
+	for _, tag := range entry.tags {
+		if keys, ok := c.tags[tag]; ok {
+			delete(keys, entry.key)
+			if len(keys) == 0 {
+				delete(c.tags, tag)
+			}
+		}
+	}
+}
+
+// cacheKey 渲染任务的缓存键：主题、模板名称、块名称和调用方提供的键
+func (job *renderJob) cacheKey() string {
+	return job.theme + "\x00" + job.tmplName + "\x00" + job.block + "\x00" + job.opt.CacheKey
+}
+
+// cachedOutput 查找渲染任务的缓存，并记录写入缓存时使用的代数。
+// 缓存中不包含调试信息，启用 Debug 时在命中的输出后追加本次请求的调试信息
+func (en *Engine) cachedOutput(job *renderJob) ([]byte, bool) {
+	if job.opt.CacheKey == "" {
+		return nil, false
+	}
+	start := time.Now()
+	output, gen, ok := en.cache.get(job.cacheKey())
+	job.cacheGen = gen
+	if ok && job.opt.Debug {
+		job.cacheHit = true
+		buf := bytes.NewBuffer(append([]byte(nil), output...))
+		job.writeDebug(buf, time.Since(start))
+		output = buf.Bytes()
+	}
+	return output, ok
+}
+
+// storeOutput 缓存渲染成功的输出
+func (en *Engine) storeOutput(job *renderJob, output []byte) {
+	if job.opt.CacheKey == "" {
+		return
+	}
+	en.cache.set(job.cacheGen, job.cacheKey(), append([]byte(nil), output...), job.opt.CacheTTL, job.opt.CacheTags)
+}
+
+// InvalidateCache 删除带有任一指定标签的输出缓存，返回删除的条目数
+func (en *Engine) InvalidateCache(tags ...string) int {
+	return en.cache.invalidate(tags...)
+}
+
+// PurgeCache 清空输出缓存。模板重载和主题切换时会自动清空。
+func (en *Engine) PurgeCache() {
+	en.cache.purge()
+}
@@ -0,0 +1,187 @@
+package template
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestOutputCache 测试输出缓存的淘汰、过期和标签失效
+func TestOutputCache(t *testing.T) {
+	t.Run("LRU", func(t *testing.T) {
+		c := newOutputCache(2)
+		_, gen, _ := c.get("a")
+		c.set(gen, "a", []byte("A"), 0, nil)
+		c.set(gen, "b", []byte("B"), 0, nil)
+		// 访问 a 后 b 成为最久未使用的条目
+		_, _, ok := c.get("a")
+		require.True(t, ok)
+		c.set(gen, "c", []byte("C"), 0, nil)
+
+		_, _, ok = c.get("b")
+		assert.False(t, ok)
+		_, _, ok = c.get("a")
+		assert.True(t, ok)
+		assert.Equal(t, 2, c.len())
+	})
+
+	t.Run("TTL", func(t *testing.T) {
+		now := time.Now()
+		c := newOutputCache(10)
+		c.now = func() time.Time { return now }
+		c.set(0, "a", []byte("A"), time.Minute, nil)
+
+		_, _, ok := c.get("a")
+		assert.True(t, ok)
+
+		now = now.Add(time.Minute)
+		_, _, ok = c.get("a")
+		assert.False(t, ok)
+		assert.Zero(t, c.len())
+	})
+
+	t.Run("Tags", func(t *testing.T) {
+		c := newOutputCache(10)
+		c.set(0, "list", []byte("L"), 0, []string{"posts"})
+		c.set(0, "detail", []byte("D"), 0, []string{"posts", "post:1"})
+		c.set(0, "about", []byte("A"), 0, nil)
+
+		assert.Equal(t, 1, c.invalidate("post:1"))
+		assert.Equal(t, 1, c.invalidate("posts"))
+		assert.Equal(t, 0, c.invalidate("posts"))
+		_, _, ok := c.get("about")
+		assert.True(t, ok)
+	})
+
+	t.Run("PurgeDiscardsStaleResults", func(t *testing.T) {
+		c := newOutputCache(10)
+		_, gen, _ := c.get("a")
+		c.purge()
+		// 清空前开始的渲染不应写入缓存
+		c.set(gen, "a", []byte("stale"), 0, nil)
+		assert.Zero(t, c.len())
+	})
+
+	t.Run("Disabled", func(t *testing.T) {
+		c := newOutputCache(0)
+		c.set(0, "a", []byte("A"), 0, nil)
+		assert.Zero(t, c.len())
+	})
+}
+
+// TestRenderCache 测试渲染时使用输出缓存
+func TestRenderCache(t *testing.T) {
+	testDir := setupMultiThemeTestDir(t)
+	defer os.RemoveAll(testDir)
+
+	engine, err := NewEngine(testDir, DefaultLoadTemplate, nil,
+		EnableMultiTheme(true),
+		DefaultTheme("default"),
+	)
+	require.NoError(t, err)
+	defer engine.Close()
+	require.NoError(t, engine.Init())
+
+	render := func(title string, opts ...Option) string {
+		var buf bytes.Buffer
+		require.NoError(t, engine.RenderPage(&buf, "test", H{"title": title}, opts...))
+		return buf.String()
+	}
+
+	t.Run("HitAndInvalidate", func(t *testing.T) {
+		first := render("第一次", Cache("test", time.Minute, "pages"))
+		assert.Contains(t, first, "第一次")
+		// 缓存键相同时直接返回缓存的输出
+		assert.Equal(t, first, render("第二次", Cache("test", time.Minute, "pages")))
+		// 未指定缓存时正常渲染
+		assert.Contains(t, render("第二次"), "第二次")
+
+		assert.Equal(t, 1, engine.InvalidateCache("pages"))
+		assert.Contains(t, render("第三次", Cache("test", time.Minute, "pages")), "第三次")
+	})
+
+	t.Run("KeyedByTheme", func(t *testing.T) {
+		engine.PurgeCache()
+		render("默认", Cache("theme", 0))
+		dark := render("深色", Cache("theme", 0), SetTheme("dark"))
+		assert.Contains(t, dark, "background: #1a1a1a")
+		assert.Contains(t, dark, "深色")
+	})
+
+	t.Run("PurgedOnSwitchAndReload", func(t *testing.T) {
+		engine.PurgeCache()
+		render("切换前", Cache("switch", 0))
+
+		require.NoError(t, engine.SwitchTheme("dark"))
+		assert.Zero(t, engine.cache.len())
+		render("重载前", Cache("switch", 0))
+
+		require.NoError(t, engine.ReloadCurrentTheme())
+		assert.Contains(t, render("重载后", Cache("switch", 0)), "重载后")
+	})
+
+	t.Run("HTTP", func(t *testing.T) {
+		engine.PurgeCache()
+		for i := 0; i < 2; i++ {
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			require.NoError(t, engine.HTMLPage(rec, req, "test", H{"title": fmt.Sprint(i)}, Cache("http", 0), StatusCode(http.StatusAccepted)))
+			assert.Equal(t, http.StatusAccepted, rec.Code)
+			assert.Contains(t, rec.Body.String(), "<h1>0</h1>")
+		}
+	})
+}
+
+// TestFragmentCache 测试模板中的片段缓存
+func TestFragmentCache(t *testing.T) {
+	testDir := t.TempDir()
+	require.NoError(t, createLegacyStructure(testDir))
+
+	sidebarContent := `{{ define "sidebar" }}<aside>{{ count }} {{ .name }}</aside>{{ end }}`
+	require.NoError(t, os.WriteFile(filepath.Join(testDir, "partials", "sidebar.tmpl"), []byte(sidebarContent), 0644))
+	pageDir := filepath.Join(testDir, "pages", "home")
+	require.NoError(t, os.MkdirAll(pageDir, 0755))
+	pageContent := `{{ define "header" }}<title>{{ .title }}</title>{{ end }}
+{{ define "content" }}<h1>{{ .title }}</h1>{{ cache "sidebar" 300 "sidebar" . }}{{ end }}`
+	require.NoError(t, os.WriteFile(filepath.Join(pageDir, "home.tmpl"), []byte(pageContent), 0644))
+
+	calls := 0
+	funcMap := FuncMap{
+		"count": func() int {
+			calls++
+			return calls
+		},
+	}
+	engine, err := NewEngine(testDir, DefaultLoadTemplate, funcMap)
+	require.NoError(t, err)
+	defer engine.Close()
+	require.NoError(t, engine.Init())
+
+	render := func(title string) string {
+		var buf bytes.Buffer
+		require.NoError(t, engine.RenderPage(&buf, "home", H{"title": title, "name": "<b>"}))
+		return buf.String()
+	}
+
+	first := render("第一次")
+	assert.Contains(t, first, "<aside>1 &lt;b&gt;</aside>")
+	// 页面其余部分照常渲染，片段使用缓存
+	second := render("第二次")
+	assert.Contains(t, second, "<h1>第二次</h1>")
+	assert.Contains(t, second, "<aside>1 &lt;b&gt;</aside>")
+
+	assert.Equal(t, 1, engine.InvalidateCache("fragment:sidebar"))
+	assert.Contains(t, render("失效后"), "<aside>2 &lt;b&gt;</aside>")
+
+	// 重载模板后缓存被清空
+	require.NoError(t, engine.ReloadCurrentTheme())
+	assert.Contains(t, render("重载后"), "<aside>3 &lt;b&gt;</aside>")
+}
@@ -29,7 +29,9 @@ func TestFinalCompatibilityVerification(t *testing.T) {
 			t.Fatalf("Failed to create engine with legacy API: %v", err)
 		}
 
-		engine.Init()
+		if err := engine.Init(); err != nil {
+			t.Fatalf("Failed to initialize engine: %v", err)
+		}
 		defer engine.Close()
 
 		// 验证所有传统API方法都正常工作
@@ -116,7 +118,9 @@ func TestFinalCompatibilityVerification(t *testing.T) {
 			t.Fatalf("Failed to create engine with legacy options: %v", err)
 		}
 
-		optEngine.Init()
+		if err := optEngine.Init(); err != nil {
+			t.Fatalf("Failed to initialize engine: %v", err)
+		}
 		defer optEngine.Close()
 
 		// 验证选项正确应用
@@ -173,7 +177,9 @@ func TestFinalCompatibilityVerification(t *testing.T) {
 			t.Fatalf("Failed to create multi-theme engine: %v", err)
 		}
 
-		multiEngine.Init()
+		if err := multiEngine.Init(); err != nil {
+			t.Fatalf("Failed to initialize engine: %v", err)
+		}
 		defer multiEngine.Close()
 
 		// 验证所有传统API仍然工作
@@ -370,7 +376,9 @@ func TestAPIConsistency(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Failed to create legacy engine: %v", err)
 	}
-	legacyEngine.Init()
+	if err := legacyEngine.Init(); err != nil {
+		t.Fatalf("Failed to initialize engine: %v", err)
+	}
 	defer legacyEngine.Close()
 
 	// 创建单主题的多主题引擎
@@ -384,7 +392,9 @@ func TestAPIConsistency(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Failed to create multi-theme engine: %v", err)
 	}
-	multiEngine.Init()
+	if err := multiEngine.Init(); err != nil {
+		t.Fatalf("Failed to initialize engine: %v", err)
+	}
 	defer multiEngine.Close()
 
 	// 测试API一致性
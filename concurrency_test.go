@@ -0,0 +1,136 @@
+package template
+
+import (
+	"bytes"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// renderConcurrently 在多个协程中持续渲染，直到 stop 关闭
+func renderConcurrently(t *testing.T, engine *Engine, stop <-chan struct{}, render func(buf *bytes.Buffer) error) *sync.WaitGroup {
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				var buf bytes.Buffer
+				if err := render(&buf); err != nil {
+					t.Errorf("render failed: %v", err)
+					return
+				}
+				if buf.Len() == 0 {
+					t.Errorf("render produced empty output")
+					return
+				}
+			}
+		}()
+	}
+	return &wg
+}
+
+// TestConcurrentRenderDuringReload 测试重载模板时并发渲染（配合 -race 运行）
+func TestConcurrentRenderDuringReload(t *testing.T) {
+	testDir := setupMultiThemeTestDir(t)
+	defer os.RemoveAll(testDir)
+
+	engine, err := NewEngine(testDir, DefaultLoadTemplate, nil,
+		EnableMultiTheme(true),
+		DefaultTheme("default"),
+	)
+	require.NoError(t, err)
+	defer engine.Close()
+	require.NoError(t, engine.Init())
+
+	stop := make(chan struct{})
+	wg := renderConcurrently(t, engine, stop, func(buf *bytes.Buffer) error {
+		return engine.RenderPage(buf, "test", H{"title": "reload"})
+	})
+
+	for i := 0; i < 10; i++ {
+		engine.reloadTemplates()
+		require.NoError(t, engine.ReloadCurrentTheme())
+	}
+	close(stop)
+	wg.Wait()
+
+	assert.Equal(t, "default", engine.GetCurrentTheme())
+	assert.NotNil(t, engine.CurrentRender())
+}
+
+// TestConcurrentRenderDuringSwitch 测试切换主题时并发渲染（配合 -race 运行）
+func TestConcurrentRenderDuringSwitch(t *testing.T) {
+	testDir := setupMultiThemeTestDir(t)
+	defer os.RemoveAll(testDir)
+
+	engine, err := NewEngine(testDir, DefaultLoadTemplate, nil,
+		EnableMultiTheme(true),
+		DefaultTheme("default"),
+	)
+	require.NoError(t, err)
+	defer engine.Close()
+	require.NoError(t, engine.Init())
+
+	stop := make(chan struct{})
+	wg := renderConcurrently(t, engine, stop, func(buf *bytes.Buffer) error {
+		if err := engine.RenderPage(buf, "test", H{"title": "switch"}); err != nil {
+			return err
+		}
+		return engine.RenderError(buf, "404", H{"title": "switch"}, SetTheme("dark"))
+	})
+
+	var queries sync.WaitGroup
+	queries.Add(1)
+	go func() {
+		defer queries.Done()
+		for i := 0; i < 100; i++ {
+			_ = engine.GetCurrentTheme()
+			_ = engine.GetAvailableThemes()
+			_ = engine.ErrorName("404")
+		}
+	}()
+
+	for i := 0; i < 20; i++ {
+		theme := "dark"
+		if i%2 == 1 {
+			theme = "default"
+		}
+		require.NoError(t, engine.SwitchTheme(theme))
+	}
+	queries.Wait()
+	close(stop)
+	wg.Wait()
+
+	assert.Equal(t, "default", engine.GetCurrentTheme())
+}
+
+// TestConcurrentLegacyReload 测试传统模式下重载模板时并发渲染
+func TestConcurrentLegacyReload(t *testing.T) {
+	testDir := t.TempDir()
+	require.NoError(t, createLegacyStructure(testDir))
+
+	engine, err := NewEngine(testDir, DefaultLoadTemplate, nil)
+	require.NoError(t, err)
+	defer engine.Close()
+	require.NoError(t, engine.Init())
+
+	stop := make(chan struct{})
+	wg := renderConcurrently(t, engine, stop, func(buf *bytes.Buffer) error {
+		return engine.RenderPage(buf, "sample", H{"title": "legacy"})
+	})
+
+	for i := 0; i < 10; i++ {
+		engine.reloadTemplates()
+	}
+	close(stop)
+	wg.Wait()
+}
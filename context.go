@@ -0,0 +1,39 @@
+package template
+
+import (
+	"context"
+	"io"
+)
+
+// RenderPageContext 使用上下文渲染页面。
+// 上下文取消或超时后停止输出并返回 ctx.Err()。默认不会把上下文写入模板数据，
+// 设置 ContextKey("context") 后以 .context 传入模板，FuncMap 中的函数可以接收
+// context.Context 参数，如 {{ currentUser .context }}。
+func (en *Engine) RenderPageContext(ctx context.Context, w io.Writer, name string, data H, opts ...Option) error {
+	return en.render(ctx, w, name, KindPage, data, opts...)
+}
+
+// RenderSingleContext 使用上下文渲染单页面，见 RenderPageContext
+func (en *Engine) RenderSingleContext(ctx context.Context, w io.Writer, name string, data H, opts ...Option) error {
+	return en.render(ctx, w, name, KindSingle, data, opts...)
+}
+
+// RenderErrorContext 使用上下文渲染错误页面，见 RenderPageContext
+func (en *Engine) RenderErrorContext(ctx context.Context, w io.Writer, name string, data H, opts ...Option) error {
+	return en.render(ctx, w, name, KindError, data, opts...)
+}
+
+// contextWriter 在每次写入前检查上下文，上下文结束后返回结束原因使模板执行中止
+type contextWriter struct {
+	ctx context.Context
+	w   io.Writer
+}
+
+// Write 实现 io.Writer
+func (cw *contextWriter) Write(p []byte) (int, error) {
+	if cw.ctx.Err() != nil {
+		// 超过 MaxExecutionTime 时为 ErrExecutionTimeout，其他情况与 ctx.Err() 相同
+		return 0, context.Cause(cw.ctx)
+	}
+	return cw.w.Write(p)
+}
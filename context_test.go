@@ -0,0 +1,110 @@
+package template
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// 测试用的上下文键
+type (
+	ctxKey    struct{}
+	cancelKey struct{}
+)
+
+// TestRenderContext 测试带上下文的渲染
+func TestRenderContext(t *testing.T) {
+	testDir := t.TempDir()
+	require.NoError(t, createLegacyStructure(testDir))
+
+	pageDir := filepath.Join(testDir, "pages", "ctx")
+	require.NoError(t, os.MkdirAll(pageDir, 0755))
+	pageContent := `{{ define "header" }}<title>{{ .title }}</title>{{ end }}
+{{ define "content" }}<p>{{ user .context }}</p>{{ stop .context }}<p>after</p>{{ end }}`
+	require.NoError(t, os.WriteFile(filepath.Join(pageDir, "ctx.tmpl"), []byte(pageContent), 0644))
+	rangeDir := filepath.Join(testDir, "pages", "ctxrange")
+	require.NoError(t, os.MkdirAll(rangeDir, 0755))
+	rangeContent := `{{ define "header" }}{{ end }}{{ define "content" }}{{ range .items }}<i>{{ . }}:{{ user $.context }}</i>{{ end }}{{ end }}`
+	require.NoError(t, os.WriteFile(filepath.Join(rangeDir, "ctxrange.tmpl"), []byte(rangeContent), 0644))
+
+	funcMap := FuncMap{
+		"user": func(ctx context.Context) string {
+			name, _ := ctx.Value(ctxKey{}).(string)
+			return name
+		},
+		// stop 取消上下文中保存的 cancel 函数，模拟渲染中途客户端断开
+		"stop": func(ctx context.Context) string {
+			if cancel, ok := ctx.Value(cancelKey{}).(context.CancelFunc); ok {
+				cancel()
+			}
+			return ""
+		},
+	}
+
+	engine, err := NewEngine(testDir, DefaultLoadTemplate, funcMap, ContextKey("context"))
+	require.NoError(t, err)
+	defer engine.Close()
+	require.NoError(t, engine.Init())
+
+	t.Run("FuncsSeeContext", func(t *testing.T) {
+		ctx := context.WithValue(context.Background(), ctxKey{}, "alice")
+		var buf bytes.Buffer
+		require.NoError(t, engine.RenderPageContext(ctx, &buf, "ctx", H{"title": "上下文"}))
+		assert.Contains(t, buf.String(), "<p>alice</p>")
+		assert.Contains(t, buf.String(), "<p>after</p>")
+	})
+
+	t.Run("RootContextInRange", func(t *testing.T) {
+		ctx := context.WithValue(context.Background(), ctxKey{}, "bob")
+		var buf bytes.Buffer
+		require.NoError(t, engine.RenderPageContext(ctx, &buf, "ctxrange", H{"items": []int{1, 2}}))
+		assert.Contains(t, buf.String(), "<i>1:bob</i><i>2:bob</i>")
+	})
+
+	t.Run("CanceledBeforeRender", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		var buf bytes.Buffer
+		err := engine.RenderSingleContext(ctx, &buf, "sample", H{"title": "单页"})
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.Zero(t, buf.Len())
+	})
+
+	t.Run("CanceledDuringRender", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		ctx = context.WithValue(ctx, cancelKey{}, cancel)
+
+		var buf bytes.Buffer
+		err := engine.RenderPageContext(ctx, &buf, "ctx", H{"title": "上下文"})
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.NotContains(t, buf.String(), "after")
+	})
+
+	t.Run("BufferedCanceledSkipsFallback", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		ctx = context.WithValue(ctx, cancelKey{}, cancel)
+
+		var buf bytes.Buffer
+		err := engine.RenderPageContext(ctx, &buf, "ctx", H{"title": "上下文"}, Buffered(true))
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.Zero(t, buf.Len())
+	})
+
+	t.Run("HTTPRequestContext", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req = req.WithContext(context.WithValue(req.Context(), ctxKey{}, "bob"))
+		rec := httptest.NewRecorder()
+		require.NoError(t, engine.HTMLPage(rec, req, "ctx", H{"title": "上下文"}))
+		assert.Contains(t, rec.Body.String(), "<p>bob</p>")
+	})
+}
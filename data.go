@@ -0,0 +1,152 @@
+package template
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+)
+
+// ErrReservedKey 模板数据中包含了引擎保留的键名
+var ErrReservedKey = errors.New("template: data key is reserved")
+
+// RenderPageData 使用任意数据渲染页面，data 可以是 H、键为字符串的 map 或结构体（及其指针），见 RenderPage。
+// 结构体只保留导出字段，模板中无法调用结构体的方法，需要调用方法时使用 RenderPageT
+func (en *Engine) RenderPageData(w io.Writer, name string, data any, opts ...Option) error {
+	return en.render(context.Background(), w, name, KindPage, data, opts...)
+}
+
+// RenderSingleData 使用任意数据渲染单页面，见 RenderPageData
+func (en *Engine) RenderSingleData(w io.Writer, name string, data any, opts ...Option) error {
+	return en.render(context.Background(), w, name, KindSingle, data, opts...)
+}
+
+// RenderErrorData 使用任意数据渲染错误页面，见 RenderPageData
+func (en *Engine) RenderErrorData(w io.Writer, name string, data any, opts ...Option) error {
+	return en.render(context.Background(), w, name, KindError, data, opts...)
+}
+
+// RenderDataContext 使用上下文和任意数据渲染模板，kind 为 KindPage、KindSingle 或 KindError，
+// 见 RenderPageContext 和 RenderPageData
+func (en *Engine) RenderDataContext(ctx context.Context, w io.Writer, kind, name string, data any, opts ...Option) error {
+	return en.render(ctx, w, name, kind, data, opts...)
+}
+
+// HTMLData 使用任意数据渲染模板并写入HTTP响应，见 HTML 和 RenderPageData
+func (en *Engine) HTMLData(w http.ResponseWriter, r *http.Request, kind, name string, data any, opts ...Option) error {
+	job, err := en.prepare(requestContext(r), name, kind, data, opts...)
+	if err != nil {
+		return err
+	}
+	return en.writeHTML(w, r, job)
+}
+
+// newDataView 将调用方的数据复制为引擎持有的模板数据，并写入全局常量、变量和上下文，调用方的数据不会被修改。
+// data 可以是 H、键为字符串的 map 或结构体（及其指针），结构体的导出字段按字段名（或 template 标签）作为键，方法不会保留。
+func newDataView(ctx context.Context, data any, opt Options) (H, error) {
+	view, err := dataFields(data)
+	if err != nil {
+		return nil, err
+	}
+
+	reserved := []struct {
+		key   string
+		value any
+	}{
+		{opt.ConstantKey, opt.GlobalConstant},
+		{opt.VariableKey, opt.GlobalVariable},
+		{opt.ContextKey, ctx},
+	}
+	for _, r := range reserved {
+		if r.key == "" {
+			continue
+		}
+		if _, ok := view[r.key]; ok {
+			return nil, fmt.Errorf("%w: %s", ErrReservedKey, r.key)
+		}
+		view[r.key] = r.value
+	}
+	return view, nil
+}
+
+// dataFields 将调用方的数据展开为新的 H
+func dataFields(data any) (H, error) {
+	switch d := data.(type) {
+	case nil:
+		return H{}, nil
+	case H:
+		return copyData(d), nil
+	case map[string]any:
+		return copyData(d), nil
+	}
+
+	v := reflect.ValueOf(data)
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return H{}, nil
+		}
+		v = v.Elem()
+	}
+
+	switch v.Kind() {
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return nil, fmt.Errorf("template: unsupported data type %T: map key must be string", data)
+		}
+		view := make(H, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			view[iter.Key().String()] = iter.Value().Interface()
+		}
+		return view, nil
+	case reflect.Struct:
+		return structFields(v), nil
+	default:
+		return nil, fmt.Errorf("template: unsupported data type %T", data)
+	}
+}
+
+// copyData 浅复制 map
+func copyData(data map[string]any) H {
+	view := make(H, len(data))
+	for k, v := range data {
+		view[k] = v
+	}
+	return view
+}
+
+// structFields 将结构体的导出字段（包括嵌入结构体提升的字段）展开为 H
+func structFields(v reflect.Value) H {
+	view := H{}
+	for _, field := range viewFields(v.Type()) {
+		value, err := v.FieldByIndexErr(field.Index)
+		if err != nil {
+			// 经过为 nil 的嵌入指针
+			continue
+		}
+		view[field.Name] = value.Interface()
+	}
+	return view
+}
+
+// viewFields 返回结构体展开为模板数据时的字段，字段名由 template 标签指定，标签为 "-" 时忽略该字段
+func viewFields(t reflect.Type) []reflect.StructField {
+	var fields []reflect.StructField
+	for _, field := range reflect.VisibleFields(t) {
+		if !field.IsExported() {
+			continue
+		}
+		if tag, ok := field.Tag.Lookup("template"); ok {
+			if tag == "-" {
+				continue
+			}
+			if tag != "" {
+				field.Name = tag
+			}
+		}
+		fields = append(fields, field)
+	}
+	return fields
+}
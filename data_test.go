@@ -0,0 +1,165 @@
+package template
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestNewDataView 测试模板数据视图
+func TestNewDataView(t *testing.T) {
+	opt := newOptions(GlobalConstant(map[string]any{"site": "blog"}), ContextKey("context"))
+	ctx := context.Background()
+
+	t.Run("ContextKeyOptIn", func(t *testing.T) {
+		// 默认不写入上下文，调用方数据中已有的 context 键不会冲突
+		view, err := newDataView(ctx, H{"context": "mine"}, newOptions())
+		require.NoError(t, err)
+		assert.Equal(t, "mine", view["context"])
+	})
+
+	t.Run("DoesNotMutateCaller", func(t *testing.T) {
+		data := H{"title": "首页"}
+		view, err := newDataView(ctx, data, opt)
+		require.NoError(t, err)
+
+		assert.Equal(t, H{"title": "首页"}, data)
+		assert.Equal(t, "首页", view["title"])
+		assert.Equal(t, opt.GlobalConstant, view["constant"])
+		assert.Equal(t, ctx, view["context"])
+	})
+
+	t.Run("ReservedKeyCollision", func(t *testing.T) {
+		_, err := newDataView(ctx, H{"constant": "mine"}, opt)
+		assert.ErrorIs(t, err, ErrReservedKey)
+	})
+
+	t.Run("CustomReservedKeys", func(t *testing.T) {
+		opt := newOptions(ReservedKeys("_const", "_var", ""))
+		view, err := newDataView(ctx, H{"constant": "mine"}, opt)
+		require.NoError(t, err)
+
+		assert.Equal(t, "mine", view["constant"])
+		assert.Contains(t, view, "_const")
+		assert.Contains(t, view, "_var")
+		assert.NotContains(t, view, "context")
+	})
+
+	t.Run("StructData", func(t *testing.T) {
+		type Base struct {
+			Title string
+		}
+		type page struct {
+			Base
+			Count  int
+			Posts  []string `template:"posts"`
+			Hidden string   `template:"-"`
+			secret string
+		}
+		view, err := newDataView(ctx, &page{Base: Base{Title: "文章"}, Count: 3, Posts: []string{"a"}, Hidden: "h", secret: "x"}, opt)
+		require.NoError(t, err)
+
+		assert.Equal(t, "文章", view["Title"])
+		assert.Equal(t, 3, view["Count"])
+		assert.Equal(t, []string{"a"}, view["posts"])
+		assert.NotContains(t, view, "Hidden")
+		assert.NotContains(t, view, "secret")
+	})
+
+	t.Run("UnsupportedType", func(t *testing.T) {
+		_, err := newDataView(ctx, []string{"a"}, opt)
+		assert.Error(t, err)
+	})
+}
+
+// dataPost 带有方法的视图模型
+type dataPost struct {
+	Title string
+}
+
+// Summary 测试方法在模板数据中的可见性
+func (p dataPost) Summary() string {
+	return "摘要：" + p.Title
+}
+
+// TestRenderSharedData 测试多个请求共用同一个数据 map 时不会产生数据竞争
+func TestRenderSharedData(t *testing.T) {
+	testDir := t.TempDir()
+	require.NoError(t, createLegacyStructure(testDir))
+
+	pageDir := filepath.Join(testDir, "pages", "post")
+	require.NoError(t, os.MkdirAll(pageDir, 0755))
+	pageContent := `{{ define "header" }}<title>{{ .Title }}</title>{{ end }}
+{{ define "content" }}<h1>{{ .Title }}</h1><p>{{ .constant.site }}</p>{{ end }}`
+	require.NoError(t, os.WriteFile(filepath.Join(pageDir, "post.tmpl"), []byte(pageContent), 0644))
+
+	for name, content := range map[string]string{
+		"summary":      `{{ define "header" }}{{ end }}{{ define "content" }}{{ .Title }}{{ .Summary }}{{ end }}`,
+		"summary-view": `{{ define "header" }}{{ end }}{{ define "content" }}{{ .Data.Summary }}{{ end }}`,
+	} {
+		require.NoError(t, os.MkdirAll(filepath.Join(testDir, "pages", name), 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(testDir, "pages", name, name+".tmpl"), []byte(content), 0644))
+	}
+
+	engine, err := NewEngine(testDir, DefaultLoadTemplate, nil, GlobalConstant(map[string]any{"site": "blog"}))
+	require.NoError(t, err)
+	defer engine.Close()
+	require.NoError(t, engine.Init())
+
+	shared := H{"Title": "共享"}
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var buf bytes.Buffer
+			assert.NoError(t, engine.RenderPage(&buf, "post", shared))
+			assert.Contains(t, buf.String(), "<p>blog</p>")
+		}()
+	}
+	wg.Wait()
+	assert.Len(t, shared, 1)
+
+	t.Run("Struct", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, engine.RenderPageData(&buf, "post", struct{ Title string }{Title: "结构体"}))
+		assert.Contains(t, buf.String(), "<h1>结构体</h1>")
+
+		buf.Reset()
+		require.NoError(t, engine.RenderDataContext(context.Background(), &buf, KindPage, "post", &struct{ Title string }{Title: "指针"}))
+		assert.Contains(t, buf.String(), "<h1>指针</h1>")
+
+		rec := httptest.NewRecorder()
+		require.NoError(t, engine.HTMLData(rec, httptest.NewRequest(http.MethodGet, "/", nil), KindPage, "post", struct{ Title string }{Title: "响应"}))
+		assert.Contains(t, rec.Body.String(), "<h1>响应</h1>")
+	})
+
+	t.Run("StructMethodsNotKept", func(t *testing.T) {
+		// 结构体展开为字段，方法不会保留，与 map 中不存在的键一样输出为空
+		var buf bytes.Buffer
+		require.NoError(t, engine.RenderPageData(&buf, "summary", dataPost{Title: "标题"}))
+		assert.Contains(t, buf.String(), "标题")
+		assert.NotContains(t, buf.String(), "摘要")
+		view, err := newDataView(context.Background(), &dataPost{Title: "标题"}, newOptions())
+		require.NoError(t, err)
+		assert.NotContains(t, view, "Summary")
+
+		// 类型检查同样只接受字段
+		err = NewTypeChecker().Register(KindPage, "summary", dataPost{}).Check(engine.CurrentRender())
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), ".Summary")
+
+		// 需要调用方法时使用泛型方法，模板中通过 .Data 访问原始的结构体
+		buf.Reset()
+		require.NoError(t, RenderPageT(engine, &buf, "summary-view", dataPost{Title: "标题"}))
+		assert.Contains(t, buf.String(), "摘要：标题")
+	})
+}
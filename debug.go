@@ -0,0 +1,215 @@
+package template
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"text/template/parse"
+	"time"
+)
+
+// writeDebug 在输出末尾追加 HTML 注释，列出主题、模板名称、组成模板的文件、执行的块和渲染耗时
+func (job *renderJob) writeDebug(buf *bytes.Buffer, elapsed time.Duration) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "theme:    %s\n", job.theme)
+	fmt.Fprintf(&b, "template: %s\n", job.tmplName)
+	if job.block != "" {
+		fmt.Fprintf(&b, "block:    %s\n", job.block)
+	}
+	if job.opt.CacheKey != "" {
+		status := "miss"
+		if job.cacheHit {
+			status = "hit"
+		}
+		fmt.Fprintf(&b, "cache:    %s %s\n", status, job.opt.CacheKey)
+	}
+	if t, ok := job.render[job.tmplName]; ok {
+		b.WriteString("files:\n")
+		for _, file := range templateFiles(t) {
+			fmt.Fprintf(&b, "  %s\n", file)
+		}
+		entry := t.Name()
+		if job.block != "" {
+			entry = job.block
+		}
+		if job.cacheHit {
+			b.WriteString("executed blocks: none, served from cache\n")
+		} else if job.traced {
+			fmt.Fprintf(&b, "executed blocks: %s\n", strings.Join(job.executedBlocks(entry), ", "))
+		} else {
+			b.WriteString("executed blocks: not recorded, enable Debug in the engine options\n")
+		}
+	}
+	fmt.Fprintf(&b, "time:     %s\n", elapsed)
+
+	buf.WriteString("\n<!-- template debug\n")
+	// 注释中不能出现 --
+	buf.WriteString(strings.ReplaceAll(b.String(), "--", "- -"))
+	buf.WriteString("-->\n")
+}
+
+// executedBlocks 按首次执行的顺序返回本次渲染执行的块，不包括入口模板 entry
+func (job *renderJob) executedBlocks(entry string) []string {
+	if job.blocks == nil {
+		return nil
+	}
+	var blocks []string
+	for _, name := range job.blocks.names {
+		if name != entry {
+			blocks = append(blocks, name)
+		}
+	}
+	return blocks
+}
+
+// templateFiles 返回组成模板集合的文件，按路径排序
+func templateFiles(t *template.Template) []string {
+	seen := make(map[string]bool)
+	var files []string
+	for _, tmpl := range t.Templates() {
+		if tmpl.Tree == nil || tmpl.Tree.ParseName == "" || seen[tmpl.Tree.ParseName] {
+			continue
+		}
+		seen[tmpl.Tree.ParseName] = true
+		files = append(files, tmpl.Tree.ParseName)
+	}
+	sort.Strings(files)
+	return files
+}
+
+// blockRecorderFuncName 记录块执行的函数名称，由 recordBlocks 插入到每个模板的开头
+const blockRecorderFuncName = "_debugBlock"
+
+// blockRecorder 一次调试渲染中执行的块
+type blockRecorder struct {
+	names []string
+	seen  map[string]bool
+}
+
+var (
+	// blockRecorders 正在记录的渲染，按执行模板的 goroutine 区分并发的渲染
+	blockRecorders sync.Map
+	// recordingBlocks 正在记录的渲染数量，为 0 时记录函数直接返回
+	recordingBlocks atomic.Int64
+)
+
+// recordBlock 模板开始执行时调用，当前 goroutine 正在进行调试渲染时记录块的名称。
+// 模板在调用 Execute 的 goroutine 中同步执行，因此可以按 goroutine 找到本次渲染
+func recordBlock(name string) string {
+	if recordingBlocks.Load() == 0 {
+		return ""
+	}
+	if r, ok := blockRecorders.Load(goroutineID()); ok {
+		rec := r.(*blockRecorder)
+		if !rec.seen[name] {
+			rec.seen[name] = true
+			rec.names = append(rec.names, name)
+		}
+	}
+	return ""
+}
+
+// startRecording 开始记录当前 goroutine 执行的块，返回的函数结束记录并将结果保存到 job.blocks
+func (job *renderJob) startRecording() func() {
+	id := goroutineID()
+	rec := &blockRecorder{seen: make(map[string]bool)}
+	prev, nested := blockRecorders.Swap(id, rec)
+	recordingBlocks.Add(1)
+	return func() {
+		recordingBlocks.Add(-1)
+		if nested {
+			blockRecorders.Store(id, prev)
+		} else {
+			blockRecorders.Delete(id)
+		}
+		job.blocks = rec
+	}
+}
+
+// goroutineID 返回当前 goroutine 的编号
+func goroutineID() uint64 {
+	var buf [64]byte
+	b := bytes.TrimPrefix(buf[:runtime.Stack(buf[:], false)], []byte("goroutine "))
+	if i := bytes.IndexByte(b, ' '); i >= 0 {
+		b = b[:i]
+	}
+	id, _ := strconv.ParseUint(string(b), 10, 64)
+	return id
+}
+
+// recordBlocks 在渲染器中每个模板的开头插入 {{ $_ := _debugBlock "name" }}，调试渲染时据此记录执行的块。
+// 插入的是变量声明，不产生输出，也不参与 html/template 的转义；需要在模板首次执行前调用
+func recordBlocks(render Render) Render {
+	for _, t := range render {
+		t.Funcs(template.FuncMap{blockRecorderFuncName: recordBlock})
+		for _, tmpl := range t.Templates() {
+			if tmpl.Tree == nil || tmpl.Tree.Root == nil {
+				continue
+			}
+			root := tmpl.Tree.Root
+			if len(root.Nodes) > 0 && isBlockRecorder(root.Nodes[0]) {
+				continue
+			}
+			root.Nodes = append([]parse.Node{blockRecorderNode(tmpl.Name())}, root.Nodes...)
+		}
+	}
+	return render
+}
+
+// blockRecorderNode 创建记录 name 块执行的语句
+func blockRecorderNode(name string) parse.Node {
+	text := "{{ $_ := " + blockRecorderFuncName + " " + strconv.Quote(name) + " }}"
+	trees, err := parse.Parse("debug", text, "{{", "}}", map[string]any{blockRecorderFuncName: recordBlock})
+	if err != nil {
+		panic(err)
+	}
+	return trees["debug"].Root.Nodes[0]
+}
+
+// isBlockRecorder 检查节点是否为 blockRecorderNode 创建的语句
+func isBlockRecorder(node parse.Node) bool {
+	action, ok := node.(*parse.ActionNode)
+	if !ok || len(action.Pipe.Decl) != 1 || len(action.Pipe.Cmds) != 1 {
+		return false
+	}
+	ident, ok := action.Pipe.Cmds[0].Args[0].(*parse.IdentifierNode)
+	return ok && ident.Ident == blockRecorderFuncName
+}
+
+// traceBlocks 引擎选项启用了 Debug 时包装加载函数，加载的模板都记录块的执行，见 recordBlocks。
+// 记录语句会增加每个块的执行开销，因此只在引擎选项中启用 Debug 时插入
+func (en *Engine) traceBlocks() {
+	if !en.opts.Debug {
+		return
+	}
+	if load := en.loadTemplateFunc; load != nil {
+		en.loadTemplateFunc = func(templatesDir string, funcMap FuncMap) Render {
+			return recordBlocks(load(templatesDir, funcMap))
+		}
+	}
+	if load := en.loadTemplateFS; load != nil {
+		en.loadTemplateFS = func(fsys fs.FS, dir string, funcMap FuncMap) Render {
+			return recordBlocks(load(fsys, dir, funcMap))
+		}
+	}
+}
+
+// recordFileName 将刚从 file 解析出的模板的 ParseName 由解析时使用的名称（通常为文件的基本名称）改为完整路径，
+// 使执行错误和调试信息能定位到具体文件
+func recordFileName(tmpl *template.Template, parseName, file string) {
+	if parseName == file {
+		return
+	}
+	for _, t := range tmpl.Templates() {
+		if t.Tree != nil && t.Tree.ParseName == parseName {
+			t.Tree.ParseName = file
+		}
+	}
+}
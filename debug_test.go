@@ -0,0 +1,160 @@
+package template
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestDebug 测试渲染调试信息
+func TestDebug(t *testing.T) {
+	testDir := createRenderTestStructure(t, true)
+	// 路径中包含 -- 的页面
+	pageDir := filepath.Join(testDir, "pages", "a--b")
+	require.NoError(t, os.MkdirAll(pageDir, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(pageDir, "a--b.tmpl"),
+		[]byte(`{{ define "header" }}{{ end }}{{ define "content" }}ok{{ end }}`), 0644))
+
+	branchDir := filepath.Join(testDir, "pages", "branch")
+	require.NoError(t, os.MkdirAll(branchDir, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(branchDir, "branch.tmpl"), []byte(`{{ define "header" }}{{ end }}
+{{ define "content" }}{{ if .admin }}{{ template "admin" }}{{ else }}{{ template "guest" }}{{ end }}{{ end }}
+{{ define "admin" }}admin{{ end }}
+{{ define "guest" }}guest{{ end }}`), 0644))
+
+	engine, err := NewEngine(testDir, DefaultLoadTemplate, nil)
+	require.NoError(t, err)
+	defer engine.Close()
+	require.NoError(t, engine.Init())
+
+	t.Run("Disabled", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, engine.RenderPage(&buf, "sample", H{"title": "标题"}))
+		assert.NotContains(t, buf.String(), "template debug")
+	})
+
+	t.Run("Page", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, engine.RenderPage(&buf, "sample", H{"title": "标题"}, Debug(true)))
+		output := buf.String()
+
+		index := strings.Index(output, "\n<!-- template debug\n")
+		require.Greater(t, index, 0)
+		assert.Contains(t, output[:index], "<h1>标题</h1>")
+		assert.True(t, strings.HasSuffix(output, "-->\n"))
+
+		debug := output[index:]
+		assert.Contains(t, debug, "template: layout.tmpl:pages/sample\n")
+		assert.Contains(t, debug, "  "+filepath.Join(testDir, "layouts", "layout.tmpl")+"\n")
+		assert.Contains(t, debug, "  "+filepath.Join(testDir, "pages", "sample", "sample.tmpl")+"\n")
+		// 引擎选项未启用 Debug 时模板中没有记录语句
+		assert.Contains(t, debug, "executed blocks: not recorded, enable Debug in the engine options\n")
+		assert.Contains(t, debug, "time:     ")
+	})
+
+	t.Run("Fragment", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, engine.RenderFragment(&buf, KindPage, "sample", "content", H{"title": "标题"}, Debug(true)))
+		assert.Contains(t, buf.String(), "block:    content\n")
+		assert.Contains(t, buf.String(), "executed blocks: not recorded")
+	})
+
+	traced, err := NewEngine(testDir, DefaultLoadTemplate, nil, Debug(true))
+	require.NoError(t, err)
+	defer traced.Close()
+	require.NoError(t, traced.Init())
+
+	t.Run("ExecutedBlocksInPage", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, traced.RenderPage(&buf, "sample", H{"title": "标题"}))
+		assert.Contains(t, buf.String(), "executed blocks: header, content\n")
+
+		buf.Reset()
+		require.NoError(t, traced.RenderFragment(&buf, KindPage, "sample", "content", H{"title": "标题"}))
+		assert.Contains(t, buf.String(), "executed blocks: \n")
+	})
+
+	t.Run("ExecutedBlocks", func(t *testing.T) {
+		// 只列出本次渲染实际执行的块，条件分支中未执行的块不出现
+		var buf bytes.Buffer
+		require.NoError(t, traced.RenderPage(&buf, "branch", H{"admin": false}))
+		assert.Contains(t, buf.String(), "executed blocks: header, content, guest\n")
+
+		buf.Reset()
+		require.NoError(t, traced.RenderPage(&buf, "branch", H{"admin": true}))
+		assert.Contains(t, buf.String(), "executed blocks: header, content, admin\n")
+	})
+
+	t.Run("ConcurrentRenders", func(t *testing.T) {
+		var wg sync.WaitGroup
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func(admin bool) {
+				defer wg.Done()
+				var buf bytes.Buffer
+				assert.NoError(t, traced.RenderPage(&buf, "branch", H{"admin": admin}, Debug(admin)))
+				if admin {
+					assert.Contains(t, buf.String(), "executed blocks: header, content, admin\n")
+				} else {
+					assert.NotContains(t, buf.String(), "template debug")
+				}
+			}(i%2 == 0)
+		}
+		wg.Wait()
+	})
+
+	t.Run("NotAddedToFallback", func(t *testing.T) {
+		var buf bytes.Buffer
+		err := engine.RenderPage(&buf, "broken", H{"title": "标题", "user": "guest"}, Debug(true))
+		require.Error(t, err)
+		assert.NotContains(t, buf.String(), "template debug")
+	})
+
+	t.Run("WithCache", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, engine.RenderPage(&buf, "sample", H{"title": "标题"}, Debug(true), Cache("debug", time.Minute)))
+		assert.Contains(t, buf.String(), "cache:    miss debug\n")
+
+		// 缓存中不包含调试信息，命中时输出本次请求的调试信息
+		buf.Reset()
+		require.NoError(t, engine.RenderPage(&buf, "sample", H{"title": "标题"}, Debug(true), Cache("debug", time.Minute)))
+		output := buf.String()
+		assert.Equal(t, 1, strings.Count(output, "<!-- template debug"))
+		assert.Contains(t, output, "<h1>标题</h1>")
+		assert.Contains(t, output, "cache:    hit debug\n")
+		assert.Contains(t, output, "executed blocks: none, served from cache\n")
+
+		buf.Reset()
+		require.NoError(t, engine.RenderPage(&buf, "sample", H{"title": "标题"}, Cache("debug", time.Minute)))
+		assert.NotContains(t, buf.String(), "template debug")
+	})
+
+	t.Run("EscapesCommentEnd", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, engine.RenderPage(&buf, "a--b", nil, Debug(true)))
+		debug := buf.String()[strings.Index(buf.String(), "<!--")+4:]
+		assert.Equal(t, len(debug)-4, strings.Index(debug, "--"))
+		assert.Contains(t, debug, "a- -b")
+	})
+}
+
+// TestExecutionErrorFile 测试执行错误中包含模板文件的完整路径
+func TestExecutionErrorFile(t *testing.T) {
+	testDir := createRenderTestStructure(t, false)
+	engine, err := NewEngine(testDir, DefaultLoadTemplate, nil)
+	require.NoError(t, err)
+	defer engine.Close()
+	require.NoError(t, engine.Init())
+
+	var buf bytes.Buffer
+	err = engine.RenderPage(&buf, "broken", H{"title": "标题", "user": "guest"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), filepath.Join(testDir, "pages", "broken", "broken.tmpl")+":2:")
+}
@@ -1,12 +1,19 @@
 package template
 
 import (
+	"context"
 	"embed"
 	"fmt"
 	"io"
+	"io/fs"
+	"net/http"
 	"os"
+	"path"
 	"path/filepath"
+	"sort"
 	"strings"
+	"sync"
+	"sync/atomic"
 
 	"github.com/fsnotify/fsnotify"
 )
@@ -17,26 +24,92 @@ type LoadTemplateFunc func(templatesDir string, funcMap FuncMap) Render
 // LoadEmbedFSTemplateFunc 加载模板函数类
 type LoadEmbedFSTemplateFunc func(tmplFS *embed.FS, tmplFSSUbDir string, funcMap FuncMap) Render
 
+// LoadTemplateFuncE 返回错误的加载模板函数类，如 LoadTemplate
+type LoadTemplateFuncE func(templatesDir string, funcMap FuncMap) (Render, error)
+
+// Must 转换为出错时panic的 LoadTemplateFunc，引擎加载时会将panic还原为错误
+func (f LoadTemplateFuncE) Must() LoadTemplateFunc {
+	return func(templatesDir string, funcMap FuncMap) Render {
+		r, err := f(templatesDir, funcMap)
+		if err != nil {
+			panic(err)
+		}
+		return r
+	}
+}
+
+// LoadEmbedFSTemplateFuncE 返回错误的加载模板函数类，如 LoadTemplateWithEmbedFS
+type LoadEmbedFSTemplateFuncE func(tmplFS *embed.FS, tmplFSSUbDir string, funcMap FuncMap) (Render, error)
+
+// Must 转换为出错时panic的 LoadEmbedFSTemplateFunc，引擎加载时会将panic还原为错误
+func (f LoadEmbedFSTemplateFuncE) Must() LoadEmbedFSTemplateFunc {
+	return func(tmplFS *embed.FS, tmplFSSUbDir string, funcMap FuncMap) Render {
+		r, err := f(tmplFS, tmplFSSUbDir, funcMap)
+		if err != nil {
+			panic(err)
+		}
+		return r
+	}
+}
+
+// LoadFSTemplateFunc 从 fs.FS 加载模板的函数类，dir 为模板目录在文件系统中的路径
+type LoadFSTemplateFunc func(fsys fs.FS, dir string, funcMap FuncMap) Render
+
+// LoadFSTemplateFuncE 返回错误的 fs.FS 加载模板函数类，如 LoadTemplateFS
+type LoadFSTemplateFuncE func(fsys fs.FS, dir string, funcMap FuncMap) (Render, error)
+
+// Must 转换为出错时panic的 LoadFSTemplateFunc，引擎加载时会将panic还原为错误
+func (f LoadFSTemplateFuncE) Must() LoadFSTemplateFunc {
+	return func(fsys fs.FS, dir string, funcMap FuncMap) Render {
+		r, err := f(fsys, dir, funcMap)
+		if err != nil {
+			panic(err)
+		}
+		return r
+	}
+}
+
+// errorsBufferSize 错误通道的缓冲区大小
+const errorsBufferSize = 16
+
 // Engine 模板引擎
 type Engine struct {
 	templatesDir        string
 	tmplFS              *embed.FS
 	tmplFSSUbDir        string
 	watcher             *fsnotify.Watcher
-	Errors              <-chan error
+	Errors              <-chan error // 文件监听和模板重载产生的错误
+	errors              chan error
 	done                chan struct{}
 	loadTemplateFunc    LoadTemplateFunc
 	loadTemplateEmbedFS LoadEmbedFSTemplateFunc
+	fsys                fs.FS              // 模板所在的文件系统，为空时从磁盘目录 templatesDir 加载
+	loadTemplateFS      LoadFSTemplateFunc // 从 fsys 的 tmplFSSUbDir 目录加载模板
 	FuncMap             FuncMap
-	HTMLRender          Render
-	opts                Options
+	// HTMLRender 当前主题的渲染器，仅为兼容保留。
+	// 重载和主题切换时会被替换，并发读取请使用 CurrentRender。
+	HTMLRender Render
+	opts       Options
+
+	// 并发控制相关字段
+	mu      sync.Mutex                  // 串行化初始化、模板重载和主题切换
+	renders atomic.Pointer[renderSet]   // 渲染器快照，渲染路径无锁读取
+	hooks   atomic.Pointer[renderHooks] // 渲染中间件快照，见 Use
+	cache   *outputCache                // 渲染输出缓存，见 Cache
 
 	// 主题管理相关字段
 	themeManager   ThemeManager // 主题管理器
-	currentTheme   string       // 当前激活的主题名称
+	currentTheme   string       // 当前激活的主题名称，受 mu 保护
 	multiThemeMode bool         // 是否启用多主题模式
 }
 
+// renderSet 不可变的渲染器快照，发布后不再修改
+type renderSet struct {
+	theme   string            // 当前激活的主题名称
+	current Render            // 当前主题的渲染器
+	themes  map[string]Render // 所有已加载主题的渲染器
+}
+
 // NewEngine 创建一个gin引擎模板
 func NewEngine(templateDir string, tmplFunc LoadTemplateFunc, funcMap FuncMap, opts ...Option) (*Engine, error) {
 	watcher, err := fsnotify.NewWatcher()
@@ -45,82 +118,151 @@ func NewEngine(templateDir string, tmplFunc LoadTemplateFunc, funcMap FuncMap, o
 	}
 
 	options := newOptions(opts...)
+	errs := make(chan error, errorsBufferSize)
+	// 内置的加载函数按引擎的 Extensions 选项加载模板文件
+	tmplFunc = builtinLoadTemplate(tmplFunc, orDefaultExtensions(options.Extensions))
 
 	engine := &Engine{
 		templatesDir:     templateDir,
 		loadTemplateFunc: tmplFunc,
 		watcher:          watcher,
+		Errors:           errs,
+		errors:           errs,
 		done:             make(chan struct{}),
 		FuncMap:          funcMap,
 		opts:             options,
+		cache:            newOutputCache(options.CacheSize),
 		// 初始化主题相关字段
 		currentTheme:   options.Theme,
 		multiThemeMode: options.MultiThemeMode,
 	}
+	engine.traceBlocks()
 
 	return engine, nil
 }
 
 func NewEngineWithEmbedFS(tmplFS *embed.FS, tmplFSSUbDir string, tmplFunc LoadEmbedFSTemplateFunc, funcMap FuncMap, opts ...Option) (*Engine, error) {
 	options := newOptions(opts...)
+	errs := make(chan error, errorsBufferSize)
+	tmplFunc = builtinLoadEmbedFS(tmplFunc, orDefaultExtensions(options.Extensions))
 
 	engine := &Engine{
+		Errors:              errs,
+		errors:              errs,
 		tmplFS:              tmplFS,
 		tmplFSSUbDir:        tmplFSSUbDir,
 		loadTemplateEmbedFS: tmplFunc,
 		FuncMap:             funcMap,
 		opts:                options,
+		cache:               newOutputCache(options.CacheSize),
+		// 初始化主题相关字段
+		currentTheme:   options.Theme,
+		multiThemeMode: options.MultiThemeMode,
+	}
+	// 嵌入式文件系统与其他 fs.FS 使用同一套加载流程
+	if tmplFS != nil {
+		engine.fsys = tmplFS
+	}
+	if tmplFunc != nil {
+		engine.loadTemplateFS = embedLoadFunc(tmplFS, tmplFunc)
+	}
+	engine.traceBlocks()
+
+	return engine, nil
+}
+
+// NewEngineWithFS 从任意文件系统创建模板引擎，如 os.DirFS、fstest.MapFS、zip.Reader 或组合的文件系统。
+// 模板目录为文件系统的根目录，需要使用子目录时可以先调用 fs.Sub；不支持文件监听
+func NewEngineWithFS(fsys fs.FS, tmplFunc LoadFSTemplateFunc, funcMap FuncMap, opts ...Option) (*Engine, error) {
+	options := newOptions(opts...)
+	errs := make(chan error, errorsBufferSize)
+	tmplFunc = builtinLoadFS(tmplFunc, orDefaultExtensions(options.Extensions))
+
+	engine := &Engine{
+		Errors:         errs,
+		errors:         errs,
+		fsys:           fsys,
+		tmplFSSUbDir:   ".",
+		loadTemplateFS: tmplFunc,
+		FuncMap:        funcMap,
+		opts:           options,
+		cache:          newOutputCache(options.CacheSize),
 		// 初始化主题相关字段
 		currentTheme:   options.Theme,
 		multiThemeMode: options.MultiThemeMode,
 	}
+	engine.traceBlocks()
 
 	return engine, nil
 }
 
-// Init 初始化
-func (en *Engine) Init() {
+// embedLoadFunc 将嵌入式文件系统的加载函数转换为 LoadFSTemplateFunc
+func embedLoadFunc(tmplFS *embed.FS, load LoadEmbedFSTemplateFunc) LoadFSTemplateFunc {
+	return func(_ fs.FS, dir string, funcMap FuncMap) Render {
+		return load(tmplFS, dir, funcMap)
+	}
+}
+
+// Init 初始化，模板有误时返回包含全部出错文件的错误
+func (en *Engine) Init() error {
+	en.mu.Lock()
+	defer en.mu.Unlock()
+
 	// 初始化主题管理器
 	if err := en.initThemeManager(); err != nil {
-		// 如果主题管理器初始化失败，回退到传统模式
-		en.HTMLRender = en.loadTemplate()
-		return
+		// 模板本身有错误时直接返回，回退到传统模式没有意义
+		if isTemplateError(err) {
+			return err
+		}
+
+		// 主题管理器不可用（如目录结构不完整），回退到传统模式
+		set, legacyErr := en.safeLegacyRenders()
+		if legacyErr != nil {
+			return fmt.Errorf("failed to init theme manager (%v), legacy loading failed: %w", err, legacyErr)
+		}
+		en.storeRenders(set)
+		return nil
 	}
 
 	// 尝试使用主题管理器加载模板
 	if en.themeManager != nil {
-		render := en.themeManager.GetRender()
-		if render != nil {
-			en.HTMLRender = render
-			return
+		if set, err := en.managerRenders(); err == nil {
+			en.storeRenders(set)
+			return nil
 		}
 	}
 
 	// 如果主题管理器不可用，使用传统方式加载
-	en.HTMLRender = en.loadTemplate()
+	set, err := en.safeLegacyRenders()
+	if err != nil {
+		return err
+	}
+	en.storeRenders(set)
+	return nil
 }
 
 // initThemeManager 初始化主题管理器
 func (en *Engine) initThemeManager() error {
 	// 创建主题管理器
-	var themeManager ThemeManager
+	var themeManager *DefaultThemeManager
 
-	if en.tmplFS != nil {
-		// 嵌入式文件系统模式
-		themeManager = NewDefaultThemeManagerWithEmbedFS(
-			en.tmplFS,
+	if en.fsys != nil {
+		// fs.FS 模式，包括嵌入式文件系统
+		themeManager = NewDefaultThemeManagerWithFS(
+			en.fsys,
 			en.tmplFSSUbDir,
-			en.FuncMap,
-			en.loadTemplateEmbedFS,
+			en.funcMap(),
+			en.loadTemplateFS,
 		)
 	} else {
 		// 文件系统模式
 		themeManager = NewDefaultThemeManager(
 			en.templatesDir,
-			en.FuncMap,
+			en.funcMap(),
 			en.loadTemplateFunc,
 		)
 	}
+	themeManager.SetExtensions(en.watchExtensions()...)
 
 	// 发现主题
 	if err := themeManager.DiscoverThemes(); err != nil {
@@ -150,9 +292,6 @@ func (en *Engine) initThemeManager() error {
 		}
 	}
 
-	// 更新当前主题状态
-	en.currentTheme = themeManager.GetCurrentTheme()
-
 	return nil
 }
 
@@ -179,43 +318,51 @@ func (en *Engine) determineTargetTheme() string {
 
 // Watching 监听模板文件夹中是否有变动
 func (en *Engine) Watching() error {
+	// 只有磁盘目录支持文件监听，嵌入式文件系统和其他 fs.FS 都不支持
+	if en.fsys != nil {
+		return fmt.Errorf("file watching not supported for fs.FS templates, only for directories on disk")
+	}
+
 	// 检查基本条件
 	if en.watcher == nil {
 		return fmt.Errorf("watcher is nil")
 	}
 
-	// 对于嵌入式文件系统，不支持文件监听
-	if en.tmplFS != nil {
-		return fmt.Errorf("file watching not supported for embedded filesystem")
-	}
-
 	if en.templatesDir == "" {
 		return fmt.Errorf("template directory is empty")
 	}
 
-	en.Errors = en.watcher.Errors
-
 	// 启动文件监听协程
+	en.startWatchLoop(en.watcher)
+
+	// 设置监听目录
+	return en.setupWatching()
+}
+
+// startWatchLoop 启动处理监听事件的协程，监听器关闭后协程退出
+func (en *Engine) startWatchLoop(watcher *fsnotify.Watcher) {
 	go func() {
 		for {
 			select {
 			case <-en.done:
 				return
-			case event, ok := <-en.watcher.Events:
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				en.handleFileEvent(watcher, event)
+			case err, ok := <-watcher.Errors:
 				if !ok {
 					return
 				}
-				en.handleFileEvent(event)
+				en.publishError(err)
 			}
 		}
 	}()
-
-	// 设置监听目录
-	return en.setupWatching()
 }
 
 // handleFileEvent 处理文件系统事件
-func (en *Engine) handleFileEvent(event fsnotify.Event) {
+func (en *Engine) handleFileEvent(watcher *fsnotify.Watcher, event fsnotify.Event) {
 	shouldReload := false
 
 	switch event.Op {
@@ -224,7 +371,7 @@ func (en *Engine) handleFileEvent(event fsnotify.Event) {
 		if fileInfo, err := os.Stat(event.Name); err == nil {
 			if fileInfo.IsDir() {
 				// 新目录：添加到监听器
-				en.watcher.Add(event.Name)
+				watcher.Add(event.Name)
 			} else {
 				// 新文件：检查是否需要重载
 				shouldReload = en.shouldReloadForFile(event.Name)
@@ -239,7 +386,7 @@ func (en *Engine) handleFileEvent(event fsnotify.Event) {
 		// 文件或目录删除/重命名
 		if fileInfo, err := os.Stat(event.Name); err == nil && fileInfo.IsDir() {
 			// 目录删除：从监听器移除
-			en.watcher.Remove(event.Name)
+			watcher.Remove(event.Name)
 		}
 		// 对于文件删除，也需要重载模板
 		shouldReload = en.shouldReloadForFile(event.Name)
@@ -249,36 +396,36 @@ func (en *Engine) handleFileEvent(event fsnotify.Event) {
 		shouldReload = false
 	}
 
-	// 如果需要重载，执行重载操作
+	// 如果需要重载，重新加载文件所属的主题
 	if shouldReload {
-		en.reloadTemplates()
+		theme, _ := en.themeForFile(event.Name)
+		en.reloadTheme(theme)
 	}
 }
 
-// setupWatching 设置文件监听
+// setupWatching 设置文件监听，多主题模式下监听所有主题的目录
 func (en *Engine) setupWatching() error {
-	// 确定要监听的目录
-	watchDir := en.getWatchDirectory()
-
-	// 验证监听目录
-	if err := en.validateWatchDirectory(watchDir); err != nil {
-		return fmt.Errorf("cannot setup watching: %w", err)
-	}
-
-	// 遍历目录下的所有子目录并添加到监听器
-	err := filepath.Walk(watchDir, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
+	for _, watchDir := range en.getWatchDirectories() {
+		// 验证监听目录
+		if err := en.validateWatchDirectory(watchDir); err != nil {
+			return fmt.Errorf("cannot setup watching: %w", err)
 		}
-		if info.IsDir() {
-			if err := en.watcher.Add(path); err != nil {
-				return fmt.Errorf("failed to add watch path %s: %w", path, err)
+
+		// 遍历目录下的所有子目录并添加到监听器
+		err := filepath.Walk(watchDir, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if info.IsDir() {
+				if err := en.watcher.Add(path); err != nil {
+					return fmt.Errorf("failed to add watch path %s: %w", path, err)
+				}
 			}
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("failed to setup watching for directory %s: %w", watchDir, err)
 		}
-		return nil
-	})
-	if err != nil {
-		return fmt.Errorf("failed to setup watching for directory %s: %w", watchDir, err)
 	}
 
 	return nil
@@ -286,9 +433,10 @@ func (en *Engine) setupWatching() error {
 
 // getWatchDirectory 获取要监听的目录
 func (en *Engine) getWatchDirectory() string {
-	// 如果有主题管理器且当前主题不为空，监听当前主题目录
-	if en.themeManager != nil && en.currentTheme != "" {
-		if theme, err := en.themeManager.LoadTheme(en.currentTheme); err == nil {
+	// 如果主题管理器实现了 ThemeProvider 且当前主题不为空，监听当前主题目录
+	provider, ok := en.themeManager.(ThemeProvider)
+	if currentTheme := en.currentThemeName(); ok && currentTheme != "" {
+		if theme, err := provider.GetTheme(currentTheme); err == nil {
 			// 只有非嵌入式主题才需要文件监听
 			if !theme.IsEmbedded && theme.Path != "" {
 				// 验证主题路径是否存在
@@ -303,24 +451,43 @@ func (en *Engine) getWatchDirectory() string {
 	return en.templatesDir
 }
 
-// getWatchDirectories 获取所有需要监听的目录（用于多主题模式）
+// getWatchDirectories 获取所有需要监听的目录，多主题模式下为所有主题的目录
 func (en *Engine) getWatchDirectories() []string {
 	var directories []string
+	if en.themeManager != nil {
+		for _, dir := range en.themeDirectories() {
+			directories = append(directories, dir)
+		}
+		sort.Strings(directories)
+	}
+
+	// 传统模式，监听基础目录
+	if len(directories) == 0 && en.templatesDir != "" {
+		directories = append(directories, en.templatesDir)
+	}
+
+	return directories
+}
 
-	// 如果是多主题模式，可能需要监听多个目录
-	if en.IsMultiThemeMode() && en.themeManager != nil {
-		// 当前只监听活跃主题的目录
-		watchDir := en.getWatchDirectory()
-		if watchDir != "" {
-			directories = append(directories, watchDir)
+// themeDirectories 获取磁盘上各主题的目录，按主题名称索引。
+// 主题管理器未实现 ThemeProvider 时只包含当前主题和 getWatchDirectory 返回的目录
+func (en *Engine) themeDirectories() map[string]string {
+	provider, ok := en.themeManager.(ThemeProvider)
+	if !ok {
+		return map[string]string{en.currentThemeName(): en.getWatchDirectory()}
+	}
+
+	directories := make(map[string]string)
+	for _, name := range en.themeManager.GetAvailableThemes() {
+		theme, err := provider.GetTheme(name)
+		// 只有非嵌入式主题才需要文件监听
+		if err != nil || theme.IsEmbedded || theme.Path == "" {
+			continue
 		}
-	} else {
-		// 传统模式，监听基础目录
-		if en.templatesDir != "" {
-			directories = append(directories, en.templatesDir)
+		if info, err := os.Stat(theme.Path); err == nil && info.IsDir() {
+			directories[name] = theme.Path
 		}
 	}
-
 	return directories
 }
 
@@ -342,90 +509,206 @@ func (en *Engine) validateWatchDirectory(dir string) error {
 	return nil
 }
 
-// reloadTemplates 重新加载模板
+// reloadTemplates 重新加载当前主题的模板，失败时保留之前可用的模板并发布错误
 func (en *Engine) reloadTemplates() {
+	en.reloadTheme("")
+}
+
+// reloadTheme 重新加载指定主题的模板，主题为空时重新加载当前主题，失败时保留之前可用的模板并发布错误
+func (en *Engine) reloadTheme(name string) {
+	en.mu.Lock()
+	defer en.mu.Unlock()
+
+	var err error
+	if name == "" || name == en.currentThemeName() {
+		err = en.reload()
+	} else {
+		err = en.reloadThemeTemplates(name)
+	}
+	if err != nil {
+		en.publishError(err)
+	}
+}
+
+// reload 重新加载模板，调用方需持有 en.mu
+func (en *Engine) reload() error {
 	// 如果有主题管理器，使用主题管理器重新加载
 	if en.themeManager != nil {
-		if err := en.reloadCurrentThemeTemplates(); err != nil {
-			// 如果主题重载失败，尝试回退到传统方式
-			en.HTMLRender = en.loadTemplate()
-		}
-		return
+		return en.reloadCurrentThemeTemplates()
 	}
 
 	// 否则使用传统方式重新加载
-	en.HTMLRender = en.loadTemplate()
+	set, err := en.safeLegacyRenders()
+	if err != nil {
+		return err
+	}
+	en.storeRenders(set)
+	return nil
 }
 
-// reloadCurrentThemeTemplates 重新加载当前主题的模板
+// reloadCurrentThemeTemplates 重新加载当前主题的模板，调用方需持有 en.mu
 func (en *Engine) reloadCurrentThemeTemplates() error {
 	if en.themeManager == nil {
 		return fmt.Errorf("theme manager not available")
 	}
 
-	// 重新加载当前主题
+	// 重新加载当前主题，失败时主题管理器保留之前的渲染器
 	if err := en.themeManager.ReloadCurrentTheme(); err != nil {
 		return fmt.Errorf("failed to reload current theme: %w", err)
 	}
 
-	// 更新渲染器
-	if render := en.themeManager.GetRender(); render != nil {
-		en.HTMLRender = render
-		return nil
+	// 发布新的渲染器快照
+	set, err := en.managerRenders()
+	if err != nil {
+		return fmt.Errorf("failed to get render after theme reload: %w", err)
 	}
+	en.storeRenders(set)
 
-	return fmt.Errorf("failed to get render after theme reload")
+	return nil
 }
 
-// isFileInCurrentTheme 检查文件是否属于当前主题
-func (en *Engine) isFileInCurrentTheme(filePath string) bool {
-	if en.themeManager == nil || en.currentTheme == "" {
-		// 传统模式下，所有模板目录下的文件都属于当前"主题"
-		return strings.HasPrefix(filePath, en.templatesDir)
+// reloadThemeTemplates 重新加载非当前主题的模板，调用方需持有 en.mu
+func (en *Engine) reloadThemeTemplates(name string) error {
+	if en.themeManager == nil {
+		return fmt.Errorf("theme manager not available")
 	}
 
-	// 获取当前主题路径
-	currentThemePath := en.getWatchDirectory()
-	if currentThemePath == "" {
-		return false
+	// 失败时主题管理器保留该主题之前的渲染器
+	if _, err := en.themeManager.LoadTheme(name); err != nil {
+		return fmt.Errorf("failed to reload theme %s: %w", name, err)
 	}
 
-	// 检查文件是否在当前主题目录下
-	absThemePath, err := filepath.Abs(currentThemePath)
+	// 发布新的渲染器快照
+	set, err := en.managerRenders()
 	if err != nil {
-		return false
+		return fmt.Errorf("failed to get render after theme reload: %w", err)
+	}
+	en.storeRenders(set)
+
+	return nil
+}
+
+// publishError 发布监听和重载错误，缓冲区已满时丢弃，避免阻塞监听协程
+func (en *Engine) publishError(err error) {
+	if en.errors == nil {
+		return
+	}
+	select {
+	case en.errors <- err:
+	default:
+	}
+}
+
+// storeRenders 原子地发布渲染器快照，调用方需持有 en.mu
+func (en *Engine) storeRenders(set *renderSet) {
+	en.bindCacheFuncs(set)
+	en.renders.Store(set)
+	// 模板或当前主题已改变，缓存的输出不再有效
+	en.cache.purge()
+	en.HTMLRender = set.current
+	if set.theme != "" {
+		en.currentTheme = set.theme
+	}
+}
+
+// safeLegacyRenders 使用传统方式加载模板，将加载过程中的panic转换为错误
+func (en *Engine) safeLegacyRenders() (*renderSet, error) {
+	render, err := recoverLoad(en.loadTemplate)
+	if err != nil {
+		return nil, err
+	}
+	return &renderSet{current: render}, nil
+}
+
+// managerRenders 根据主题管理器的状态构建快照
+func (en *Engine) managerRenders() (*renderSet, error) {
+	render := en.themeManager.GetRender()
+	if render == nil {
+		return nil, fmt.Errorf("theme manager has no render")
+	}
+
+	set := &renderSet{
+		theme:   en.themeManager.GetCurrentTheme(),
+		current: render,
+		themes:  make(map[string]Render),
+	}
+	// 主题管理器未实现 ThemeRenderProvider 时只能使用当前主题渲染
+	if provider, ok := en.themeManager.(ThemeRenderProvider); ok {
+		for _, name := range en.themeManager.GetAvailableThemes() {
+			themeRender, err := provider.GetThemeRender(name)
+			if err != nil {
+				return nil, err
+			}
+			set.themes[name] = themeRender
+		}
+	}
+	// 当前主题始终使用管理器的当前渲染器
+	if set.theme != "" {
+		set.themes[set.theme] = render
+	}
+
+	return set, nil
+}
+
+// loadRenders 获取当前渲染器快照，尚未初始化时回退到 HTMLRender
+func (en *Engine) loadRenders() *renderSet {
+	if set := en.renders.Load(); set != nil {
+		return set
+	}
+	return &renderSet{current: en.HTMLRender}
+}
+
+// currentThemeName 获取快照中的当前主题名称
+func (en *Engine) currentThemeName() string {
+	if set := en.renders.Load(); set != nil {
+		return set.theme
+	}
+	return ""
+}
+
+// CurrentRender 获取当前主题的渲染器，可安全地与重载和主题切换并发调用
+func (en *Engine) CurrentRender() Render {
+	return en.loadRenders().current
+}
+
+// themeForFile 获取文件所属的主题，传统模式下模板目录中的文件属于当前"主题"，主题名称为空
+func (en *Engine) themeForFile(filePath string) (string, bool) {
+	if en.themeManager == nil || en.currentThemeName() == "" {
+		return "", strings.HasPrefix(filePath, en.templatesDir)
 	}
 
 	absFilePath, err := filepath.Abs(filePath)
 	if err != nil {
-		return false
+		return "", false
 	}
 
-	return strings.HasPrefix(absFilePath, absThemePath)
+	// 检查文件在哪个主题目录下
+	for name, dir := range en.themeDirectories() {
+		absThemePath, err := filepath.Abs(dir)
+		if err != nil {
+			continue
+		}
+		if absFilePath == absThemePath || strings.HasPrefix(absFilePath, absThemePath+string(filepath.Separator)) {
+			return name, true
+		}
+	}
+	return "", false
 }
 
-// shouldReloadForFile 判断文件变化是否应该触发重载
+// shouldReloadForFile 判断文件变化是否应该触发所属主题的重载
 func (en *Engine) shouldReloadForFile(filePath string) bool {
-	// 检查文件是否属于当前主题
-	if !en.isFileInCurrentTheme(filePath) {
+	// 检查文件是否属于某个主题
+	if _, ok := en.themeForFile(filePath); !ok {
 		return false
 	}
 
-	// 检查文件扩展名是否为模板文件
-	return en.isTemplateFile(filePath)
+	// 检查文件扩展名是否为模板文件，布局清单同样影响加载结果
+	return en.isTemplateFile(filePath) || filepath.Base(filePath) == layoutManifestName
 }
 
-// isTemplateFile 检查文件是否为模板文件
+// isTemplateFile 检查文件是否使用引擎的模板扩展名
 func (en *Engine) isTemplateFile(filename string) bool {
-	ext := filepath.Ext(filename)
-	templateExts := []string{".tmpl", ".html", ".gohtml", ".tpl"}
-
-	for _, validExt := range templateExts {
-		if ext == validExt {
-			return true
-		}
-	}
-	return false
+	return hasTemplateExtension(filename, en.watchExtensions())
 }
 
 // Close 关闭
@@ -442,10 +725,9 @@ func (en *Engine) Close() error {
 // loadTemplate 加载模板
 func (en *Engine) loadTemplate() Render {
 	if en.templatesDir != "" {
-		return en.loadTemplateFunc(en.templatesDir, en.FuncMap)
-	} else {
-		return en.loadTemplateEmbedFS(en.tmplFS, en.tmplFSSUbDir, en.FuncMap)
+		return en.loadTemplateFunc(en.templatesDir, en.funcMap())
 	}
+	return en.loadTemplateFS(en.fsys, en.tmplFSSUbDir, en.funcMap())
 }
 
 // PageName 页面
@@ -455,7 +737,20 @@ func (en *Engine) PageName(name string) string {
 
 // PageNameWithOptions 页面（带选项）
 func (en *Engine) PageNameWithOptions(name string, opts Options) string {
-	return fmt.Sprintf("%s:pages/%s", opts.Layout, name)
+	return en.pageName(en.CurrentRender(), name, opts)
+}
+
+// pageName 在指定渲染器中解析页面名称，布局文件使用其他模板扩展名时同样能找到，如 layout.html:pages/posts/list。
+// 渲染时没有另外指定布局，且页面只与专用布局组合时，使用专用布局，如 admin.tmpl:pages/admin/users
+func (en *Engine) pageName(render Render, name string, opts Options) string {
+	layoutExt := path.Ext(opts.Layout)
+	stem := strings.TrimSuffix(opts.Layout, layoutExt)
+	candidates := en.withExtensions(stem, layoutExt, ":pages/"+name)
+	if opts.Layout == en.opts.Layout {
+		section, _, _ := strings.Cut(name, "/")
+		candidates = append(candidates, en.withExtensions(section, layoutExt, ":pages/"+name)...)
+	}
+	return firstTemplate(render, candidates)
 }
 
 // SingleName 单页
@@ -465,9 +760,14 @@ func (en *Engine) SingleName(name string) string {
 
 // SingleNameWithOptions 单页（带选项）
 func (en *Engine) SingleNameWithOptions(name string, opts Options) string {
+	return en.singleName(en.CurrentRender(), name, opts)
+}
+
+// singleName 在指定渲染器中解析单页名称，首先使用 Suffix 扩展名，然后依次尝试其他模板扩展名
+func (en *Engine) singleName(render Render, name string, opts Options) string {
 	// 单页(singles)不使用布局，但在多主题模式下需要区分不同主题的单页
 	// 单页的核心设计理念：自包含的完整HTML页面，不依赖布局
-	return fmt.Sprintf("singles/%s.%s", name, opts.Suffix)
+	return firstTemplate(render, en.withExtensions("singles/"+name, "."+opts.Suffix, ""))
 }
 
 // ErrorName 单页
@@ -477,60 +777,183 @@ func (en *Engine) ErrorName(name string) string {
 
 // ErrorNameWithOptions 错误页面（带选项）
 func (en *Engine) ErrorNameWithOptions(name string, opts Options) string {
+	return en.errorName(en.CurrentRender(), name, opts)
+}
+
+// errorName 在指定渲染器中解析错误页面名称
+func (en *Engine) errorName(render Render, name string, opts Options) string {
+	suffix := "." + opts.Suffix
 	// 首先尝试新的分割模板格式（使用布局）
 	if en.multiThemeMode {
-		// 对于错误页面，首先尝试error.tmpl布局
-		splitTemplateName := fmt.Sprintf("error.tmpl:error/%s", name)
-		if en.HTMLRender.HasTemplate(splitTemplateName) {
-			return splitTemplateName
-		}
-		// 如果没有专用错误布局，尝试单页布局
-		splitTemplateName = fmt.Sprintf("single.tmpl:error/%s", name)
-		if en.HTMLRender.HasTemplate(splitTemplateName) {
-			return splitTemplateName
+		// 对于错误页面，首先尝试error布局，如果没有专用错误布局，尝试单页布局
+		candidates := en.withExtensions("error", suffix, ":error/"+name)
+		candidates = append(candidates, en.withExtensions("single", suffix, ":error/"+name)...)
+		for _, splitTemplateName := range candidates {
+			if render.HasTemplate(splitTemplateName) {
+				return splitTemplateName
+			}
 		}
 	}
 	// 回退到传统格式
-	return fmt.Sprintf("error/%s.%s", name, opts.Suffix)
+	return firstTemplate(render, en.withExtensions("error/"+name, suffix, ""))
 }
 
 // RenderPage 渲染页面
 func (en *Engine) RenderPage(w io.Writer, name string, data H, opts ...Option) error {
-	return en.render(w, name, "page", data, opts...)
+	return en.render(context.Background(), w, name, KindPage, data, opts...)
 }
 
 // RenderSingle 渲染单页面
 func (en *Engine) RenderSingle(w io.Writer, name string, data H, opts ...Option) error {
-	return en.render(w, name, "single", data, opts...)
+	return en.render(context.Background(), w, name, KindSingle, data, opts...)
 }
 
 // RenderError 渲染错误页面
 func (en *Engine) RenderError(w io.Writer, name string, data H, opts ...Option) error {
-	return en.render(w, name, "error", data, opts...)
+	return en.render(context.Background(), w, name, KindError, data, opts...)
 }
 
 // render 渲染
-func (en *Engine) render(w io.Writer, name, typ string, data H, opts ...Option) error {
+func (en *Engine) render(ctx context.Context, w io.Writer, name, typ string, data any, opts ...Option) error {
+	job, err := en.prepare(ctx, name, typ, data, opts...)
+	if err != nil {
+		return err
+	}
+	return en.execute(w, job)
+}
+
+// execute 优先使用缓存的输出，否则执行前置钩子后渲染，需要处理完整输出时先渲染到缓冲区
+func (en *Engine) execute(w io.Writer, job *renderJob) error {
+	if output, ok := en.cachedOutput(job); ok {
+		_, err := w.Write(output)
+		return err
+	}
+	if err := job.beforeRender(); err != nil {
+		return err
+	}
+	if job.buffered() {
+		return en.executeBuffered(w, job)
+	}
+	return job.execute(w)
+}
+
+// prepare 解析本次渲染使用的渲染器、模板名称和模板数据
+func (en *Engine) prepare(ctx context.Context, name, typ string, data any, opts ...Option) (*renderJob, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	opt := en.renderOptions(opts...)
+
+	set := en.loadRenders()
+	render, err := set.themeRender(opt.Theme, en.themeManager != nil)
+	if err != nil {
+		return nil, err
+	}
+	theme := opt.Theme
+	if theme == "" {
+		theme = set.theme
+	}
+
+	tmplName, err := en.templateName(render, name, typ, opt)
+	if err != nil {
+		return nil, err
+	}
+	if typ == KindError {
+		// 错误页面按 404 -> 4xx -> error 的顺序查找，都不存在时使用内置页面
+		if resolved, ok := en.resolveErrorName(render, name, opt); ok {
+			tmplName = resolved
+		} else {
+			render, tmplName = builtinErrorRender, builtinErrorName
+		}
+		// 未指定状态码时使用错误页面名称对应的状态码
+		if code, ok := parseStatus(name); ok && opt.StatusCode == http.StatusOK {
+			opt.StatusCode = code
+		}
+	}
+
+	view, err := newDataView(ctx, data, opt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &renderJob{
+		ctx:      ctx,
+		render:   render,
+		name:     name,
+		theme:    theme,
+		tmplName: tmplName,
+		typ:      typ,
+		data:     view,
+		opt:      opt,
+		hooks:    en.hooks.Load(),
+		traced:   en.opts.Debug,
+		readFile: en.readFile,
+	}, nil
+}
+
+// readFile 读取模板文件，路径与加载模板时使用的路径相同
+func (en *Engine) readFile(file string) ([]byte, error) {
+	if en.fsys != nil {
+		return fs.ReadFile(en.fsys, file)
+	}
+	return os.ReadFile(file)
+}
+
+// renderOptions 合并引擎选项和本次调用的选项
+func (en *Engine) renderOptions(opts ...Option) Options {
 	opt := en.opts
+	// 主题只由本次调用的 SetTheme 选项指定，未指定时使用当前主题
+	opt.Theme = ""
+	// 缓存只由本次调用的 Cache 选项指定
+	opt.CacheKey, opt.CacheTTL, opt.CacheTags = "", 0, nil
 	for _, o := range opts {
 		o(&opt)
 	}
+	return opt
+}
 
-	if data == nil {
-		data = H{}
-	}
-	data["constant"] = opt.GlobalConstant
-	data["variable"] = opt.GlobalVariable
+// templateName 根据渲染类型解析模板名称
+func (en *Engine) templateName(render Render, name, typ string, opt Options) (string, error) {
 	switch typ {
-	case "page":
-		return en.HTMLRender.Execute(en.PageNameWithOptions(name, opt), w, data)
-	case "single":
-		return en.HTMLRender.Execute(en.SingleNameWithOptions(name, opt), w, data)
-	case "error":
-		return en.HTMLRender.Execute(en.ErrorNameWithOptions(name, opt), w, data)
+	case KindPage:
+		return en.pageName(render, name, opt), nil
+	case KindSingle:
+		return en.singleName(render, name, opt), nil
+	case KindError:
+		return en.errorName(render, name, opt), nil
 	default:
-		return fmt.Errorf("unknown render type: %s", typ)
+		return "", fmt.Errorf("unknown render type: %s", typ)
+	}
+}
+
+// themeRender 获取指定主题的渲染器，主题为空时返回当前渲染器
+func (set *renderSet) themeRender(themeName string, multiTheme bool) (Render, error) {
+	if themeName == "" {
+		return set.current, nil
+	}
+
+	if !multiTheme {
+		// 传统模式下只有默认主题存在
+		if themeName == "default" {
+			return set.current, nil
+		}
+		return nil, &ThemeError{
+			Type:    ErrThemeNotFound,
+			Theme:   themeName,
+			Message: "theme not found in legacy mode",
+		}
 	}
+
+	render, ok := set.themes[themeName]
+	if !ok || render == nil {
+		return nil, &ThemeError{
+			Type:    ErrThemeNotFound,
+			Theme:   themeName,
+			Message: "theme not found",
+		}
+	}
+	return render, nil
 }
 
 // 主题相关的公共API方法
@@ -555,6 +978,9 @@ func (en *Engine) GetCurrentTheme() string {
 
 // SwitchTheme 切换到指定主题
 func (en *Engine) SwitchTheme(themeName string) error {
+	en.mu.Lock()
+	defer en.mu.Unlock()
+
 	if en.themeManager == nil {
 		return &ThemeError{
 			Type:    ErrThemeSwitchFailed,
@@ -568,50 +994,16 @@ func (en *Engine) SwitchTheme(themeName string) error {
 		return err
 	}
 
-	// 更新引擎状态
-	en.currentTheme = en.themeManager.GetCurrentTheme()
-
-	// 更新渲染器
-	if render := en.themeManager.GetRender(); render != nil {
-		en.HTMLRender = render
+	// 发布新的渲染器快照
+	if set, err := en.managerRenders(); err == nil {
+		en.storeRenders(set)
 	}
 
-	// 如果有文件监听器且不是嵌入式文件系统，更新监听目录
-	if en.watcher != nil && en.tmplFS == nil {
-		// 重新设置文件监听器以监听新主题目录
-		if err := en.updateWatcherForTheme(); err != nil {
-			// 监听器更新失败不应该阻止主题切换
-			// 只记录错误但不返回失败
-			// 可以考虑添加日志记录
-		}
-	}
+	// 所有主题的目录都已在监听，切换主题不需要更新监听器
 
 	return nil
 }
 
-// updateWatcherForTheme 为主题切换更新监听器
-func (en *Engine) updateWatcherForTheme() error {
-	if en.watcher == nil {
-		return nil
-	}
-
-	// 对于嵌入式文件系统，不需要更新监听器
-	if en.tmplFS != nil {
-		return nil
-	}
-
-	// 获取新的监听目录
-	newWatchDir := en.getWatchDirectory()
-
-	// 验证新的监听目录
-	if err := en.validateWatchDirectory(newWatchDir); err != nil {
-		return fmt.Errorf("invalid watch directory after theme switch: %w", err)
-	}
-
-	// 重新创建监听器并设置新的监听路径
-	return en.updateWatcher()
-}
-
 // ThemeExists 检查指定主题是否存在
 func (en *Engine) ThemeExists(themeName string) bool {
 	if en.themeManager == nil {
@@ -651,13 +1043,10 @@ func (en *Engine) IsMultiThemeMode() bool {
 
 // ReloadCurrentTheme 重新加载当前主题
 func (en *Engine) ReloadCurrentTheme() error {
-	if en.themeManager == nil {
-		// 传统模式下重新加载模板
-		en.HTMLRender = en.loadTemplate()
-		return nil
-	}
+	en.mu.Lock()
+	defer en.mu.Unlock()
 
-	return en.reloadCurrentThemeTemplates()
+	return en.reload()
 }
 
 // GetWatchedDirectories 获取当前正在监听的目录信息
@@ -673,13 +1062,13 @@ func (en *Engine) GetWatchedDirectories() []string {
 
 // RestartWatching 重启文件监听（用于调试和故障恢复）
 func (en *Engine) RestartWatching() error {
-	if en.watcher == nil {
-		return fmt.Errorf("watcher not initialized")
+	// 只有磁盘目录支持文件监听，嵌入式文件系统和其他 fs.FS 都不支持
+	if en.fsys != nil {
+		return fmt.Errorf("file watching not supported for fs.FS templates, only for directories on disk")
 	}
 
-	// 对于嵌入式文件系统，不支持文件监听
-	if en.tmplFS != nil {
-		return fmt.Errorf("file watching not supported for embedded filesystem")
+	if en.watcher == nil {
+		return fmt.Errorf("watcher not initialized")
 	}
 
 	// 清理现有监听器
@@ -696,39 +1085,6 @@ func (en *Engine) GetThemeManager() ThemeManager {
 	return en.themeManager
 }
 
-// updateWatcher 更新文件监听器
-func (en *Engine) updateWatcher() error {
-	if en.watcher == nil {
-		return nil
-	}
-
-	// 对于嵌入式文件系统，不需要更新监听器
-	if en.tmplFS != nil {
-		return nil
-	}
-
-	// 移除所有现有的监听路径
-	// 注意：fsnotify.Watcher 没有直接的方法来列出所有监听的路径
-	// 所以我们需要重新创建监听器
-
-	// 关闭现有监听器
-	if err := en.watcher.Close(); err != nil {
-		return fmt.Errorf("failed to close existing watcher: %w", err)
-	}
-
-	// 创建新的监听器
-	newWatcher, err := fsnotify.NewWatcher()
-	if err != nil {
-		return fmt.Errorf("failed to create new watcher: %w", err)
-	}
-
-	en.watcher = newWatcher
-	en.Errors = en.watcher.Errors
-
-	// 重新设置监听
-	return en.setupWatching()
-}
-
 // clearWatcher 清理监听器的所有路径
 func (en *Engine) clearWatcher() error {
 	if en.watcher == nil {
@@ -748,7 +1104,6 @@ func (en *Engine) clearWatcher() error {
 	}
 
 	en.watcher = newWatcher
-	en.Errors = en.watcher.Errors
 
 	return nil
 }
@@ -0,0 +1,175 @@
+package template
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// receiveError 从引擎错误通道中读取一个错误
+func receiveError(t *testing.T, engine *Engine) error {
+	t.Helper()
+	select {
+	case err := <-engine.Errors:
+		return err
+	case <-time.After(time.Second):
+		t.Fatal("expected an error to be published")
+		return nil
+	}
+}
+
+// TestReloadKeepsLastGoodTemplates 测试模板有语法错误时重载不会panic并保留之前的模板
+func TestReloadKeepsLastGoodTemplates(t *testing.T) {
+	t.Run("Legacy", func(t *testing.T) {
+		testDir := t.TempDir()
+		require.NoError(t, createLegacyStructure(testDir))
+
+		engine, err := NewEngine(testDir, DefaultLoadTemplate, nil)
+		require.NoError(t, err)
+		defer engine.Close()
+		require.NoError(t, engine.Init())
+
+		badFile := filepath.Join(testDir, "pages", "sample", "sample.tmpl")
+		require.NoError(t, os.WriteFile(badFile, []byte("{{ define \"content\" }}\n<h1>{{ .title }</h1>\n{{ end }}"), 0644))
+
+		assert.NotPanics(t, engine.reloadTemplates)
+
+		var parseErr *ParseError
+		require.ErrorAs(t, receiveError(t, engine), &parseErr)
+		assert.Equal(t, badFile, parseErr.File)
+		assert.Equal(t, 2, parseErr.Line)
+		assert.NotEmpty(t, parseErr.Message)
+
+		// 仍然使用之前可用的模板
+		var buf bytes.Buffer
+		require.NoError(t, engine.RenderPage(&buf, "sample", H{"title": "仍然可用"}))
+		assert.Contains(t, buf.String(), "仍然可用")
+	})
+
+	t.Run("MultiTheme", func(t *testing.T) {
+		testDir := setupMultiThemeTestDir(t)
+		defer os.RemoveAll(testDir)
+
+		engine, err := NewEngine(testDir, DefaultLoadTemplate, nil,
+			EnableMultiTheme(true),
+			DefaultTheme("default"),
+		)
+		require.NoError(t, err)
+		defer engine.Close()
+		require.NoError(t, engine.Init())
+
+		badFile := filepath.Join(testDir, "default", "layouts", "layout.tmpl")
+		require.NoError(t, os.WriteFile(badFile, []byte("<html>\n{{ template \"content\" . }\n</html>"), 0644))
+
+		err = engine.ReloadCurrentTheme()
+		require.Error(t, err)
+
+		var parseErr *ParseError
+		require.ErrorAs(t, err, &parseErr)
+		assert.Equal(t, badFile, parseErr.File)
+		assert.Equal(t, 2, parseErr.Line)
+
+		var buf bytes.Buffer
+		require.NoError(t, engine.RenderPage(&buf, "test", H{"title": "仍然可用"}))
+		assert.Contains(t, buf.String(), "background: #ffffff")
+	})
+}
+
+// TestNewParseError 测试解析错误信息的提取
+func TestNewParseError(t *testing.T) {
+	files := []string{"/themes/default/layouts/layout.tmpl", "/themes/default/pages/list/content.tmpl"}
+
+	parseErr := newParseError(errors.New(`template: content.tmpl:12: unexpected "}" in operand`), files...)
+	assert.Equal(t, "/themes/default/pages/list/content.tmpl", parseErr.File)
+	assert.Equal(t, 12, parseErr.Line)
+	assert.Equal(t, `unexpected "}" in operand`, parseErr.Message)
+
+	parseErr = newParseError(errors.New("something went wrong"))
+	assert.Empty(t, parseErr.File)
+	assert.Zero(t, parseErr.Line)
+	assert.Equal(t, "template parse error: something went wrong", parseErr.Error())
+}
+
+// TestLoadTemplateAggregatesErrors 测试加载函数汇总所有出错的文件
+func TestLoadTemplateAggregatesErrors(t *testing.T) {
+	testDir := t.TempDir()
+	require.NoError(t, createLegacyStructure(testDir))
+
+	badPartial := filepath.Join(testDir, "partials", "sample.tmpl")
+	badSingle := filepath.Join(testDir, "singles", "sample.tmpl")
+	require.NoError(t, os.WriteFile(badPartial, []byte("<div>{{ .name </div>"), 0644))
+	require.NoError(t, os.WriteFile(badSingle, []byte("<h1>\n{{ if .title }}</h1>"), 0644))
+
+	render, err := LoadTemplate(testDir, nil)
+	require.Error(t, err)
+	assert.Nil(t, render)
+
+	var loadErrs LoadErrors
+	require.ErrorAs(t, err, &loadErrs)
+
+	// 局部模板出现在每个页面中，但只报告一次
+	files := make([]string, 0, len(loadErrs))
+	for _, e := range loadErrs {
+		var parseErr *ParseError
+		require.ErrorAs(t, e, &parseErr)
+		files = append(files, parseErr.File)
+	}
+	assert.ElementsMatch(t, []string{badPartial, badSingle}, files)
+
+	// 兼容的加载函数仍然panic
+	assert.Panics(t, func() { DefaultLoadTemplate(testDir, nil) })
+}
+
+// TestInitReturnsTemplateErrors 测试 Init 返回模板错误而不是panic或静默回退
+func TestInitReturnsTemplateErrors(t *testing.T) {
+	t.Run("Legacy", func(t *testing.T) {
+		testDir := t.TempDir()
+		require.NoError(t, createLegacyStructure(testDir))
+		require.NoError(t, os.WriteFile(filepath.Join(testDir, "layouts", "layout.tmpl"), []byte("{{ template \"content\" . "), 0644))
+
+		engine, err := NewEngine(testDir, DefaultLoadTemplate, nil)
+		require.NoError(t, err)
+		defer engine.Close()
+
+		err = engine.Init()
+		require.Error(t, err)
+
+		var parseErr *ParseError
+		require.ErrorAs(t, err, &parseErr)
+		assert.Equal(t, filepath.Join(testDir, "layouts", "layout.tmpl"), parseErr.File)
+	})
+
+	t.Run("MultiTheme", func(t *testing.T) {
+		testDir := setupMultiThemeTestDir(t)
+		defer os.RemoveAll(testDir)
+		require.NoError(t, os.WriteFile(filepath.Join(testDir, "dark", "pages", "test", "test.tmpl"), []byte("{{ define \"content\" }}{{ end"), 0644))
+
+		engine, err := NewEngine(testDir, DefaultLoadTemplate, nil, EnableMultiTheme(true))
+		require.NoError(t, err)
+		defer engine.Close()
+
+		err = engine.Init()
+		require.Error(t, err)
+
+		var parseErr *ParseError
+		require.ErrorAs(t, err, &parseErr)
+		assert.Equal(t, filepath.Join(testDir, "dark", "pages", "test", "test.tmpl"), parseErr.File)
+	})
+
+	t.Run("Valid", func(t *testing.T) {
+		testDir := t.TempDir()
+		require.NoError(t, createLegacyStructure(testDir))
+
+		engine, err := NewEngine(testDir, LoadTemplateFuncE(LoadTemplate).Must(), nil)
+		require.NoError(t, err)
+		defer engine.Close()
+
+		require.NoError(t, engine.Init())
+	})
+}
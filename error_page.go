@@ -0,0 +1,98 @@
+package template
+
+import (
+	"context"
+	"html/template"
+	"net/http"
+	"strconv"
+)
+
+// builtinErrorName 内置错误页面的模板名称
+const builtinErrorName = "builtin:error"
+
+// builtinErrorRender 主题中没有可用的错误页面时使用的内置最简错误页面
+var builtinErrorRender = Render{
+	builtinErrorName: template.Must(template.New(builtinErrorName).Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>{{ .status }} {{ .message }}</title></head>
+<body>
+<h1>{{ .status }} {{ .message }}</h1>
+{{ with .requestID }}<p>Request ID: {{ . }}</p>{{ end }}
+</body>
+</html>
+`)),
+}
+
+// requestIDKey 请求ID的上下文键
+type requestIDKey struct{}
+
+// requestIDHeader 未通过 WithRequestID 设置请求ID时读取的请求头
+const requestIDHeader = "X-Request-ID"
+
+// WithRequestID 返回带有请求ID的上下文，错误页面的模板数据中以 requestID 传入
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID 获取 WithRequestID 设置的请求ID
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// errorCandidates 错误页面的查找顺序，如 404 -> 4xx -> error
+func errorCandidates(name string) []string {
+	candidates := []string{name}
+	if _, ok := parseStatus(name); ok {
+		candidates = append(candidates, name[:1]+"xx")
+	}
+	if name != "error" {
+		candidates = append(candidates, "error")
+	}
+	return candidates
+}
+
+// resolveErrorName 按查找顺序返回主题中第一个存在的错误页面
+func (en *Engine) resolveErrorName(render Render, name string, opt Options) (string, bool) {
+	for _, candidate := range errorCandidates(name) {
+		tmplName := en.errorName(render, candidate, opt)
+		if render.HasTemplate(tmplName) {
+			return tmplName, true
+		}
+	}
+	return "", false
+}
+
+// parseStatus 解析三位数字的HTTP状态码
+func parseStatus(name string) (int, bool) {
+	if len(name) != 3 {
+		return 0, false
+	}
+	code, err := strconv.Atoi(name)
+	if err != nil || code < 100 || code > 599 {
+		return 0, false
+	}
+	return code, true
+}
+
+// addErrorData 为错误页面补充状态码、状态说明和请求ID，调用方已提供的值不会被覆盖
+func (job *renderJob) addErrorData() {
+	if job.typ != KindError {
+		return
+	}
+	status := job.opt.StatusCode
+	defaults := H{
+		"status":  status,
+		"message": http.StatusText(status),
+	}
+	if id := RequestID(job.ctx); id != "" {
+		defaults["requestID"] = id
+	} else if job.request != nil && job.request.Header.Get(requestIDHeader) != "" {
+		defaults["requestID"] = job.request.Header.Get(requestIDHeader)
+	}
+	for key, value := range defaults {
+		if _, ok := job.data[key]; !ok {
+			job.data[key] = value
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package template
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestErrorPageResolution 测试错误页面的查找顺序和模板数据
+func TestErrorPageResolution(t *testing.T) {
+	testDir := t.TempDir()
+	require.NoError(t, createLegacyStructure(testDir))
+	pages := map[string]string{
+		"404.tmpl":   `<p>404 page {{ .status }} {{ .message }}</p>`,
+		"4xx.tmpl":   `<p>4xx page {{ .status }} {{ .message }} {{ .requestID }}</p>`,
+		"error.tmpl": `<p>generic page {{ .status }} {{ .message }}</p>`,
+	}
+	for name, content := range pages {
+		require.NoError(t, os.WriteFile(filepath.Join(testDir, "errors", name), []byte(content), 0644))
+	}
+
+	engine, err := NewEngine(testDir, DefaultLoadTemplate, nil)
+	require.NoError(t, err)
+	defer engine.Close()
+	require.NoError(t, engine.Init())
+
+	renderError := func(ctx context.Context, name string, data H) string {
+		var buf bytes.Buffer
+		require.NoError(t, engine.RenderErrorContext(ctx, &buf, name, data))
+		return buf.String()
+	}
+
+	t.Run("ExactPage", func(t *testing.T) {
+		assert.Equal(t, "<p>404 page 404 Not Found</p>", renderError(context.Background(), "404", nil))
+	})
+
+	t.Run("StatusClass", func(t *testing.T) {
+		ctx := WithRequestID(context.Background(), "req-1")
+		assert.Equal(t, "<p>4xx page 403 Forbidden req-1</p>", renderError(ctx, "403", nil))
+	})
+
+	t.Run("Generic", func(t *testing.T) {
+		assert.Equal(t, "<p>generic page 502 Bad Gateway</p>", renderError(context.Background(), "502", nil))
+	})
+
+	t.Run("CallerDataWins", func(t *testing.T) {
+		assert.Equal(t, "<p>404 page 404 页面不存在</p>", renderError(context.Background(), "404", H{"message": "页面不存在"}))
+	})
+
+	t.Run("HTTPStatusAndRequestID", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/missing", nil)
+		req.Header.Set("X-Request-ID", "req-2")
+		rec := httptest.NewRecorder()
+		require.NoError(t, engine.HTMLError(rec, req, "410", nil))
+
+		assert.Equal(t, http.StatusGone, rec.Code)
+		assert.Equal(t, "<p>4xx page 410 Gone req-2</p>", rec.Body.String())
+	})
+
+	t.Run("BuiltinPage", func(t *testing.T) {
+		require.NoError(t, os.Remove(filepath.Join(testDir, "errors", "error.tmpl")))
+		require.NoError(t, engine.ReloadCurrentTheme())
+
+		ctx := WithRequestID(context.Background(), "req-3")
+		page := renderError(ctx, "503", nil)
+		assert.Contains(t, page, "<h1>503 Service Unavailable</h1>")
+		assert.Contains(t, page, "<p>Request ID: req-3</p>")
+	})
+}
@@ -0,0 +1,284 @@
+package template
+
+import (
+	"errors"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	texttemplate "text/template"
+)
+
+// LoadErrors 加载模板时收集到的全部错误，每个出错的文件对应一项
+type LoadErrors []error
+
+// Error 实现error接口
+func (e LoadErrors) Error() string {
+	if len(e) == 1 {
+		return e[0].Error()
+	}
+	messages := make([]string, 0, len(e))
+	for _, err := range e {
+		messages = append(messages, err.Error())
+	}
+	return fmt.Sprintf("%d template errors: %s", len(e), strings.Join(messages, "; "))
+}
+
+// Unwrap 支持 errors.Is 和 errors.As 检查其中的每个错误
+func (e LoadErrors) Unwrap() []error {
+	return e
+}
+
+// add 添加错误，展开嵌套的 LoadErrors 并忽略重复的错误（同一局部模板会出现在多个页面中）
+func (e *LoadErrors) add(err error) {
+	if nested, ok := err.(LoadErrors); ok {
+		for _, item := range nested {
+			e.add(item)
+		}
+		return
+	}
+	for _, existing := range *e {
+		if existing.Error() == err.Error() {
+			return
+		}
+	}
+	*e = append(*e, err)
+}
+
+// err 没有错误时返回nil，避免返回带类型的空值
+func (e LoadErrors) err() error {
+	if len(e) == 0 {
+		return nil
+	}
+	return e
+}
+
+// ParseError 模板解析错误，记录出错的文件和行号
+type ParseError struct {
+	File    string // 出错的模板文件路径
+	Line    int    // 出错的行号，未知时为0
+	Message string // 错误信息
+	Err     error  // 原始错误
+}
+
+// Error 实现error接口
+func (e *ParseError) Error() string {
+	switch {
+	case e.File != "" && e.Line > 0:
+		return fmt.Sprintf("template parse error: %s:%d: %s", e.File, e.Line, e.Message)
+	case e.File != "":
+		return fmt.Sprintf("template parse error: %s: %s", e.File, e.Message)
+	default:
+		return fmt.Sprintf("template parse error: %s", e.Message)
+	}
+}
+
+// Unwrap 支持错误链
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// parseErrorPattern 匹配 text/template 与 html/template 的错误格式，如 "template: list.tmpl:3: unexpected EOF"
+var parseErrorPattern = regexp.MustCompile(`^(?:html/)?template: ?([^:]+):(\d+)(?::\d+)?:\s*(.*)$`)
+
+// newParseError 将解析模板时的原始错误转换为ParseError，files 用于把模板名还原为文件路径
+func newParseError(err error, files ...string) *ParseError {
+	var parseErr *ParseError
+	if errors.As(err, &parseErr) {
+		return parseErr
+	}
+
+	result := &ParseError{
+		Message: err.Error(),
+		Err:     err,
+	}
+
+	// 读取文件失败
+	var pathErr *fs.PathError
+	if errors.As(err, &pathErr) {
+		result.File = pathErr.Path
+		result.Message = pathErr.Err.Error()
+		return result
+	}
+
+	matches := parseErrorPattern.FindStringSubmatch(err.Error())
+	if matches == nil {
+		return result
+	}
+
+	result.File = matches[1]
+	result.Line, _ = strconv.Atoi(matches[2])
+	result.Message = matches[3]
+
+	// 模板名为文件的基本名称，还原为完整路径
+	for _, file := range files {
+		if filepath.Base(file) == matches[1] {
+			result.File = file
+			break
+		}
+	}
+
+	return result
+}
+
+// isTemplateError 判断错误是否由模板文件本身引起
+func isTemplateError(err error) bool {
+	var parseErr *ParseError
+	return errors.As(err, &parseErr)
+}
+
+// recoverLoad 执行模板加载函数，并将加载过程中的panic转换为错误
+func recoverLoad(load func() Render) (render Render, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			switch v := r.(type) {
+			case LoadErrors, *ParseError:
+				err = v.(error)
+			case error:
+				err = newParseError(v)
+			default:
+				err = &ParseError{Message: fmt.Sprint(v)}
+			}
+			render = nil
+		}
+	}()
+
+	return load(), nil
+}
+
+// ErrTemplateNotFound 渲染器中不存在指定名称的模板
+var ErrTemplateNotFound = errors.New("template not exists")
+
+// templateNotFoundError 模板不存在错误，保留原来的错误信息 template <name> not exists，
+// 并满足 errors.Is(err, ErrTemplateNotFound)
+type templateNotFoundError struct {
+	name string
+}
+
+// Error 实现 error 接口
+func (e *templateNotFoundError) Error() string {
+	return fmt.Sprintf("template %s not exists", e.name)
+}
+
+// Is 支持 errors.Is(err, ErrTemplateNotFound)
+func (e *templateNotFoundError) Is(target error) bool {
+	return target == ErrTemplateNotFound
+}
+
+// TemplateError 模板执行错误，定位到出错的主题模板文件
+type TemplateError struct {
+	Theme   string // 主题名称，传统模式下为 default
+	Name    string // 模板集合名称，如 layout.tmpl:pages/posts/list
+	File    string // 出错的模板文件路径，未知时为空
+	Line    int    // 出错的行号，未知时为0
+	Column  int    // 出错的列号（从1开始的字节偏移），未知时为0
+	Excerpt string // 出错位置附近的源码，读取不到文件时为空
+	Message string // 去掉位置前缀的错误信息
+	Err     error  // 原始错误
+}
+
+// Error 实现error接口
+func (e *TemplateError) Error() string {
+	name := e.Name
+	if e.Theme != "" {
+		name = e.Theme + "/" + e.Name
+	}
+	switch {
+	case e.File != "" && e.Column > 0:
+		return fmt.Sprintf("template %s: %s:%d:%d: %s", name, e.File, e.Line, e.Column, e.Message)
+	case e.File != "" && e.Line > 0:
+		return fmt.Sprintf("template %s: %s:%d: %s", name, e.File, e.Line, e.Message)
+	default:
+		return fmt.Sprintf("template %s: %s", name, e.Message)
+	}
+}
+
+// Unwrap 支持错误链
+func (e *TemplateError) Unwrap() error {
+	return e.Err
+}
+
+// execErrorPattern 匹配模板执行错误的位置前缀，如 "template: pages/list/list.tmpl:3:5: executing ..."
+var execErrorPattern = regexp.MustCompile(`(?s)^(?:html/)?template: ?(.+?):(\d+)(?::(\d+))?: (.*)$`)
+
+// excerptContext 源码摘录中出错行前后的行数
+const excerptContext = 2
+
+// newTemplateError 将模板执行错误转换为 *TemplateError，其他错误（如写入错误）原样返回。
+// readFile 用于读取出错的文件以生成源码摘录，可以为 nil
+func newTemplateError(err error, theme, name string, readFile func(file string) ([]byte, error)) error {
+	var execErr texttemplate.ExecError
+	var escapeErr *template.Error
+	if !errors.As(err, &execErr) && !errors.As(err, &escapeErr) {
+		return err
+	}
+
+	result := &TemplateError{
+		Theme:   theme,
+		Name:    name,
+		Message: err.Error(),
+		Err:     err,
+	}
+	matches := execErrorPattern.FindStringSubmatch(err.Error())
+	if matches == nil {
+		return result
+	}
+	result.File = matches[1]
+	result.Line, _ = strconv.Atoi(matches[2])
+	// text/template 报告的列号从0开始
+	if column, convErr := strconv.Atoi(matches[3]); convErr == nil {
+		result.Column = column + 1
+	}
+	result.Message = matches[4]
+
+	if readFile != nil {
+		if src, readErr := readFile(result.File); readErr == nil {
+			result.Excerpt = sourceExcerpt(src, result.Line, result.Column)
+		}
+	}
+	return result
+}
+
+// sourceExcerpt 返回第 line 行前后几行带行号的源码，出错行以 > 标记，已知列号时在下一行用 ^ 指出位置
+func sourceExcerpt(src []byte, line, column int) string {
+	lines := strings.Split(string(src), "\n")
+	if line < 1 || line > len(lines) {
+		return ""
+	}
+	first := max(line-excerptContext, 1)
+	last := min(line+excerptContext, len(lines))
+	width := len(strconv.Itoa(last))
+
+	var b strings.Builder
+	for i := first; i <= last; i++ {
+		text := strings.TrimRight(lines[i-1], "\r")
+		marker := " "
+		if i == line {
+			marker = ">"
+		}
+		fmt.Fprintf(&b, "%s %*d | %s\n", marker, width, i, text)
+		if i == line && column > 0 {
+			fmt.Fprintf(&b, "  %*s | %s^\n", width, "", caretIndent(text, column-1))
+		}
+	}
+	return b.String()
+}
+
+// caretIndent 返回与 text 前 n 个字节等宽的缩进，保留其中的制表符
+func caretIndent(text string, n int) string {
+	if n > len(text) {
+		n = len(text)
+	}
+	var b strings.Builder
+	for _, r := range text[:n] {
+		if r == '\t' {
+			b.WriteByte('\t')
+		} else {
+			b.WriteByte(' ')
+		}
+	}
+	return b.String()
+}
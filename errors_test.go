@@ -0,0 +1,92 @@
+package template
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	texttemplate "text/template"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestTemplateError 测试模板执行错误定位到主题文件
+func TestTemplateError(t *testing.T) {
+	testDir := createRenderTestStructure(t, false)
+	engine, err := NewEngine(testDir, DefaultLoadTemplate, nil)
+	require.NoError(t, err)
+	defer engine.Close()
+	require.NoError(t, engine.Init())
+
+	t.Run("ExecutionError", func(t *testing.T) {
+		var buf bytes.Buffer
+		err := engine.RenderPage(&buf, "broken", H{"title": "标题", "user": "guest"})
+
+		var tmplErr *TemplateError
+		require.True(t, errors.As(err, &tmplErr))
+		file := filepath.Join(testDir, "pages", "broken", "broken.tmpl")
+		assert.Equal(t, "default", tmplErr.Theme)
+		assert.Equal(t, "layout.tmpl:pages/broken", tmplErr.Name)
+		assert.Equal(t, file, tmplErr.File)
+		assert.Equal(t, 2, tmplErr.Line)
+		assert.Equal(t, 52, tmplErr.Column)
+		assert.Contains(t, tmplErr.Message, `executing "content" at <.user.Name>`)
+		assert.Equal(t, "  1 | {{ define \"header\" }}<title>{{ .title }}</title>{{ end }}\n"+
+			"> 2 | {{ define \"content\" }}<h1>{{ .title }}</h1>{{ .user.Name }}{{ end }}\n"+
+			"    | "+"                                                   ^\n", tmplErr.Excerpt)
+		assert.Contains(t, err.Error(), "template default/layout.tmpl:pages/broken: "+file+":2:52: ")
+
+		// 仍然可以取得标准库的原始错误
+		var execErr texttemplate.ExecError
+		assert.True(t, errors.As(err, &execErr))
+	})
+
+	t.Run("Fragment", func(t *testing.T) {
+		var buf bytes.Buffer
+		err := engine.RenderFragment(&buf, KindPage, "broken", "content", H{"title": "标题", "user": "guest"})
+		var tmplErr *TemplateError
+		require.True(t, errors.As(err, &tmplErr))
+		assert.Equal(t, 2, tmplErr.Line)
+	})
+
+	t.Run("TemplateNotFound", func(t *testing.T) {
+		var buf bytes.Buffer
+		err := engine.RenderPage(&buf, "missing", nil)
+		assert.ErrorIs(t, err, ErrTemplateNotFound)
+		assert.EqualError(t, err, "template layout.tmpl:pages/missing not exists")
+
+		var tmplErr *TemplateError
+		assert.False(t, errors.As(err, &tmplErr))
+		assert.ErrorIs(t, NewRender().ExecuteBlock("missing", "content", &buf, nil), ErrTemplateNotFound)
+	})
+
+	t.Run("MissingSource", func(t *testing.T) {
+		err := newTemplateError(texttemplate.ExecError{
+			Name: "list.tmpl",
+			Err:  errors.New("template: list.tmpl:3:4: executing \"list.tmpl\" at <.x>: boom"),
+		}, "default", "layout.tmpl:pages/list", os.ReadFile)
+
+		var tmplErr *TemplateError
+		require.True(t, errors.As(err, &tmplErr))
+		assert.Equal(t, "list.tmpl", tmplErr.File)
+		assert.Equal(t, 3, tmplErr.Line)
+		assert.Equal(t, 5, tmplErr.Column)
+		assert.Empty(t, tmplErr.Excerpt)
+	})
+
+	t.Run("OtherErrors", func(t *testing.T) {
+		writeErr := errors.New("broken pipe")
+		assert.Same(t, writeErr, newTemplateError(writeErr, "default", "x", nil))
+		assert.NoError(t, newTemplateError(nil, "default", "x", nil))
+	})
+}
+
+// TestSourceExcerpt 测试源码摘录
+func TestSourceExcerpt(t *testing.T) {
+	src := []byte("a\nb\n\tc {{ .x }}\nd\ne\nf")
+	assert.Equal(t, "  1 | a\n  2 | b\n> 3 | \tc {{ .x }}\n    | \t  ^\n  4 | d\n  5 | e\n", sourceExcerpt(src, 3, 4))
+	assert.Equal(t, "  4 | d\n  5 | e\n> 6 | f\n", sourceExcerpt(src, 6, 0))
+	assert.Empty(t, sourceExcerpt(src, 7, 1))
+}
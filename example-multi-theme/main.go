@@ -45,7 +45,9 @@ func main() {
 	if err != nil {
 		log.Fatalf("创建模板引擎失败: %s\n", err)
 	}
-	engine.Init()
+	if err = engine.Init(); err != nil {
+		log.Fatalf("初始化模板引擎失败: %s\n", err)
+	}
 
 	// 设置初始主题为默认主题
 	err = engine.SwitchTheme("default")
@@ -35,7 +35,9 @@ func main() {
 	if err != nil {
 		log.Fatalf("创建模板引擎失败: %s\n", err)
 	}
-	engine.Init()
+	if err = engine.Init(); err != nil {
+		log.Fatalf("初始化模板引擎失败: %s\n", err)
+	}
 
 	err = engine.Watching()
 	if err != nil {
@@ -108,10 +110,9 @@ func postsListHandler(w http.ResponseWriter, r *http.Request) {
 		},
 	}
 
-	w.Header().Set("Content-Type", "text/html; charset=utf-8")
-	if err := engine.RenderPage(w, "posts/list", data); err != nil {
+	if err := engine.HTMLPage(w, r, "posts/list", data); err != nil {
+		// HTMLPage 等方法已写入状态码（模板不存在时为 404，其他错误为 500），这里只记录错误
 		log.Printf("渲染页面失败: %s\n", err)
-		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 	}
 }
 
@@ -124,10 +125,9 @@ func postsDetailHandler(w http.ResponseWriter, r *http.Request) {
 		"createdAt": time.Now(),
 	}
 
-	w.Header().Set("Content-Type", "text/html; charset=utf-8")
-	if err := engine.RenderPage(w, "posts/detail", data); err != nil {
+	if err := engine.HTMLPage(w, r, "posts/detail", data); err != nil {
+		// HTMLPage 等方法已写入状态码（模板不存在时为 404，其他错误为 500），这里只记录错误
 		log.Printf("渲染页面失败: %s\n", err)
-		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 	}
 }
 
@@ -137,26 +137,21 @@ func loginHandler(w http.ResponseWriter, r *http.Request) {
 		"title": "用户登录",
 	}
 
-	w.Header().Set("Content-Type", "text/html; charset=utf-8")
-	if err := engine.RenderSingle(w, "login", data); err != nil {
+	if err := engine.HTMLSingle(w, r, "login", data); err != nil {
+		// HTMLPage 等方法已写入状态码（模板不存在时为 404，其他错误为 500），这里只记录错误
 		log.Printf("渲染页面失败: %s\n", err)
-		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 	}
 }
 
 // errorHandler 错误页面
 func errorHandler(w http.ResponseWriter, r *http.Request, status int) {
-	w.Header().Set("Content-Type", "text/html; charset=utf-8")
-	w.WriteHeader(status)
-
 	data := template.H{
 		"title":   fmt.Sprintf("%d 错误", status),
 		"message": http.StatusText(status),
 		"path":    r.URL.Path,
 	}
 
-	if err := engine.RenderError(w, fmt.Sprintf("%d", status), data); err != nil {
+	if err := engine.HTMLError(w, r, fmt.Sprintf("%d", status), data, template.StatusCode(status)); err != nil {
 		log.Printf("渲染错误页面失败: %s\n", err)
-		http.Error(w, http.StatusText(status), status)
 	}
 }
@@ -0,0 +1,149 @@
+package template
+
+import (
+	"errors"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"reflect"
+	"strings"
+)
+
+// defaultExtensions 默认加载和解析名称时使用的模板文件扩展名
+var defaultExtensions = []string{".tmpl"}
+
+// defaultWatchExtensions 未设置 Extensions 时监听文件变化和校验主题识别的扩展名，与之前的版本保持一致
+var defaultWatchExtensions = []string{".tmpl", ".html", ".gohtml", ".tpl"}
+
+// normalizeExtensions 为扩展名补充前导点并去除空值和重复值
+func normalizeExtensions(exts []string) []string {
+	var normalized []string
+	seen := make(map[string]bool, len(exts))
+	for _, ext := range exts {
+		if ext == "" {
+			continue
+		}
+		if !strings.HasPrefix(ext, ".") {
+			ext = "." + ext
+		}
+		if seen[ext] {
+			continue
+		}
+		seen[ext] = true
+		normalized = append(normalized, ext)
+	}
+	return normalized
+}
+
+// orDefaultExtensions exts 为空时返回默认扩展名
+func orDefaultExtensions(exts []string) []string {
+	if len(exts) > 0 {
+		return exts
+	}
+	return defaultExtensions
+}
+
+// hasTemplateExtension 检查文件名是否使用 exts 中的扩展名
+func hasTemplateExtension(name string, exts []string) bool {
+	ext := filepath.Ext(name)
+	for _, validExt := range exts {
+		if ext == validExt {
+			return true
+		}
+	}
+	return false
+}
+
+// globTemplates 按名称顺序返回 dir 目录中使用 exts 扩展名的模板文件，目录不存在时返回空
+func globTemplates(fsys fs.FS, dir string, exts []string) ([]string, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var files []string
+	for _, entry := range entries {
+		if !entry.IsDir() && hasTemplateExtension(entry.Name(), exts) {
+			files = append(files, path.Join(dir, entry.Name()))
+		}
+	}
+	return files, nil
+}
+
+// findLayout 按 exts 的顺序查找名称为 stem 的布局文件，如 layouts/single.html，不存在时返回空
+func findLayout(fsys fs.FS, dir, stem string, exts []string) ([]string, error) {
+	for _, ext := range exts {
+		layout := path.Join(dir, "layouts", stem+ext)
+		if _, err := fs.Stat(fsys, layout); err == nil {
+			return []string{layout}, nil
+		} else if !errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+	}
+	return nil, nil
+}
+
+// sameFunc 检查两个函数值是否为同一个函数
+func sameFunc(a, b any) bool {
+	return reflect.ValueOf(a).Pointer() == reflect.ValueOf(b).Pointer()
+}
+
+// builtinLoadTemplate load 为内置的 DefaultLoadTemplate 时返回加载 exts 扩展名文件的版本，自定义加载函数保持不变
+func builtinLoadTemplate(load LoadTemplateFunc, exts []string) LoadTemplateFunc {
+	if load != nil && sameFunc(load, DefaultLoadTemplate) {
+		return LoadTemplateWithExtensions(exts...).Must()
+	}
+	return load
+}
+
+// builtinLoadEmbedFS load 为内置的 DefaultLoadTemplateWithEmbedFS 时返回加载 exts 扩展名文件的版本
+func builtinLoadEmbedFS(load LoadEmbedFSTemplateFunc, exts []string) LoadEmbedFSTemplateFunc {
+	if load != nil && sameFunc(load, DefaultLoadTemplateWithEmbedFS) {
+		return LoadTemplateWithEmbedFSExtensions(exts...).Must()
+	}
+	return load
+}
+
+// builtinLoadFS load 为内置的 DefaultLoadTemplateFS 时返回加载 exts 扩展名文件的版本
+func builtinLoadFS(load LoadFSTemplateFunc, exts []string) LoadFSTemplateFunc {
+	if load != nil && sameFunc(load, DefaultLoadTemplateFS) {
+		return LoadTemplateFSWithExtensions(exts...).Must()
+	}
+	return load
+}
+
+// extensions 返回引擎的模板文件扩展名
+func (en *Engine) extensions() []string {
+	return orDefaultExtensions(en.opts.Extensions)
+}
+
+// watchExtensions 返回监听文件变化和校验主题时识别的扩展名，设置了 Extensions 时只识别设置的扩展名
+func (en *Engine) watchExtensions() []string {
+	if len(en.opts.Extensions) > 0 {
+		return en.opts.Extensions
+	}
+	return defaultWatchExtensions
+}
+
+// withExtensions 返回 stem+扩展名+rest 形式的候选模板名称，首先使用 preferred 扩展名，然后依次使用引擎的扩展名
+func (en *Engine) withExtensions(stem, preferred, rest string) []string {
+	candidates := []string{stem + preferred + rest}
+	for _, ext := range en.extensions() {
+		if ext != preferred {
+			candidates = append(candidates, stem+ext+rest)
+		}
+	}
+	return candidates
+}
+
+// firstTemplate 返回 candidates 中第一个存在于渲染器中的模板名称，都不存在时返回第一个候选名称
+func firstTemplate(render Render, candidates []string) string {
+	for _, candidate := range candidates {
+		if render.HasTemplate(candidate) {
+			return candidate
+		}
+	}
+	return candidates[0]
+}
@@ -0,0 +1,180 @@
+package template
+
+import (
+	"bytes"
+	"os"
+	"path"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// htmlThemeFS 返回模板文件使用 .html 扩展名的内存文件系统
+func htmlThemeFS(dir string) fstest.MapFS {
+	files := map[string]string{
+		"layouts/layout.html":        `<main>{{ template "content" . }}{{ template "footer" }}</main>`,
+		"layouts/single.html":        `<div class="single">{{ template "content" . }}</div>`,
+		"pages/posts/list/list.html": `{{ define "content" }}<h1>{{ .title }}</h1>{{ end }}`,
+		"partials/footer.html":       `{{ define "footer" }}<footer>html</footer>{{ end }}`,
+		"partials/notes.txt":         `{{ if }}`,
+		"singles/about.html":         `<p>about html</p>`,
+		"singles/about.gohtml":       `<p>about gohtml</p>`,
+		"errors/404.html":            `<p>404 html</p>`,
+		"errors/500/500.html":        `{{ define "content" }}<p>500 html</p>{{ end }}`,
+	}
+	fsys := fstest.MapFS{}
+	for name, content := range files {
+		fsys[path.Join(dir, name)] = &fstest.MapFile{Data: []byte(content)}
+	}
+	return fsys
+}
+
+// TestExtensions 测试模板扩展名在加载、名称解析、监听和主题校验中一致生效
+func TestExtensions(t *testing.T) {
+	t.Run("Loading", func(t *testing.T) {
+		// 默认只加载 .tmpl 文件
+		render, err := LoadTemplateFS(htmlThemeFS("site"), "site", nil)
+		require.NoError(t, err)
+		assert.False(t, render.HasTemplate("layout.html:pages/posts/list"))
+		assert.False(t, render.HasTemplate("singles/about.html"))
+
+		render, err = LoadTemplateFSWithExtensions(".tmpl", ".html", ".gohtml")(htmlThemeFS("site"), "site", nil)
+		require.NoError(t, err)
+		assert.True(t, render.HasTemplate("layout.html:pages/posts/list"))
+		assert.False(t, render.HasTemplate("single.html:pages/posts/list"))
+		assert.True(t, render.HasTemplate("singles/about.html"))
+		assert.True(t, render.HasTemplate("singles/about.gohtml"))
+		assert.True(t, render.HasTemplate("error/404.html"))
+		assert.True(t, render.HasTemplate("single.html:error/500"))
+	})
+
+	t.Run("LoaderVariants", func(t *testing.T) {
+		render, err := LoadTemplateFSWithExtensions("gohtml")(htmlThemeFS("site"), "site", nil)
+		require.NoError(t, err)
+		assert.Len(t, render, 1)
+		assert.True(t, render.HasTemplate("singles/about.gohtml"))
+
+		testDir := t.TempDir()
+		require.NoError(t, os.MkdirAll(filepath.Join(testDir, "pages"), 0755))
+		require.NoError(t, os.MkdirAll(filepath.Join(testDir, "singles"), 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(testDir, "singles", "about.html"), []byte("about"), 0644))
+		require.NoError(t, os.WriteFile(filepath.Join(testDir, "singles", "login.tmpl"), []byte("login"), 0644))
+		render, err = LoadTemplateWithExtensions(".html")(testDir, nil)
+		require.NoError(t, err)
+		assert.Len(t, render, 1)
+		assert.True(t, render.HasTemplate("singles/about.html"))
+	})
+
+	t.Run("NotInFuncMap", func(t *testing.T) {
+		engine := &Engine{opts: newOptions(Extensions(".html"))}
+		assert.NotContains(t, engine.funcMap(), "templateExtensions")
+	})
+
+	t.Run("NameResolution", func(t *testing.T) {
+		engine, err := NewEngineWithFS(htmlThemeFS("."), DefaultLoadTemplateFS, nil, Extensions(".tmpl", ".html", ".gohtml"))
+		require.NoError(t, err)
+		defer engine.Close()
+		require.NoError(t, engine.Init())
+
+		var buf bytes.Buffer
+		require.NoError(t, engine.RenderPage(&buf, "posts/list", H{"title": "文章"}))
+		assert.Equal(t, "<main><h1>文章</h1><footer>html</footer></main>", buf.String())
+
+		// 首先使用 Suffix 指定的扩展名，然后按 Extensions 的顺序尝试
+		buf.Reset()
+		require.NoError(t, engine.RenderSingle(&buf, "about", nil))
+		assert.Equal(t, "<p>about html</p>", buf.String())
+		buf.Reset()
+		require.NoError(t, engine.RenderSingle(&buf, "about", nil, Suffix("gohtml")))
+		assert.Equal(t, "<p>about gohtml</p>", buf.String())
+
+		buf.Reset()
+		require.NoError(t, engine.RenderError(&buf, "404", nil))
+		assert.Equal(t, "<p>404 html</p>", buf.String())
+
+		assert.Equal(t, "singles/about.html", engine.SingleName("about"))
+		assert.Equal(t, "singles/missing.tmpl", engine.SingleName("missing"))
+	})
+
+	t.Run("Restricted", func(t *testing.T) {
+		// 内置的加载函数使用引擎的 Extensions
+		engine, err := NewEngineWithFS(htmlThemeFS("."), DefaultLoadTemplateFS, nil, Extensions("gohtml"))
+		require.NoError(t, err)
+		defer engine.Close()
+		require.NoError(t, engine.Init())
+
+		assert.Len(t, engine.CurrentRender(), 1)
+		assert.True(t, engine.CurrentRender().HasTemplate("singles/about.gohtml"))
+		var buf bytes.Buffer
+		require.NoError(t, engine.RenderSingle(&buf, "about", nil))
+		assert.Equal(t, "<p>about gohtml</p>", buf.String())
+	})
+
+	t.Run("BuiltinLoaders", func(t *testing.T) {
+		// 无法解析的 .tpl 文件不属于引擎的扩展名，不应被加载
+		fsys := fstest.MapFS{
+			"layouts/layout.tmpl":  &fstest.MapFile{Data: []byte(`<main>{{ template "content" . }}</main>`)},
+			"pages/home/home.tmpl": &fstest.MapFile{Data: []byte(`{{ define "content" }}home{{ end }}`)},
+			"singles/broken.tpl":   &fstest.MapFile{Data: []byte(`{{ if }}`)},
+		}
+		engine, err := NewEngineWithFS(fsys, DefaultLoadTemplateFS, nil, Extensions(".tmpl"))
+		require.NoError(t, err)
+		defer engine.Close()
+		require.NoError(t, engine.Init())
+		assert.False(t, engine.CurrentRender().HasTemplate("singles/broken.tpl"))
+
+		testDir := t.TempDir()
+		for name, file := range fsys {
+			full := filepath.Join(testDir, filepath.FromSlash(name))
+			require.NoError(t, os.MkdirAll(filepath.Dir(full), 0755))
+			require.NoError(t, os.WriteFile(full, file.Data, 0644))
+		}
+		diskEngine, err := NewEngine(testDir, DefaultLoadTemplate, nil, Extensions(".tmpl"))
+		require.NoError(t, err)
+		defer diskEngine.Close()
+		require.NoError(t, diskEngine.Init())
+		assert.False(t, diskEngine.CurrentRender().HasTemplate("singles/broken.tpl"))
+	})
+
+	t.Run("DefaultTmplOnly", func(t *testing.T) {
+		// 未设置 Extensions 时只加载 .tmpl，页面目录中的其他文件不影响初始化
+		fsys := fstest.MapFS{
+			"layouts/layout.tmpl": &fstest.MapFile{Data: []byte(`<main>{{ template "content" . }}</main>`)},
+			"pages/a/a.tmpl":      &fstest.MapFile{Data: []byte(`{{ define "content" }}a{{ end }}`)},
+			"pages/a/readme.html": &fstest.MapFile{Data: []byte(`<p>{{ .Missing }</p>`)},
+		}
+		engine, err := NewEngineWithFS(fsys, DefaultLoadTemplateFS, nil)
+		require.NoError(t, err)
+		defer engine.Close()
+		require.NoError(t, engine.Init())
+
+		var buf bytes.Buffer
+		require.NoError(t, engine.RenderPage(&buf, "a", nil))
+		assert.Equal(t, "<main>a</main>", buf.String())
+	})
+
+	t.Run("Watching", func(t *testing.T) {
+		engine := &Engine{opts: newOptions(Extensions(".html"))}
+		assert.True(t, engine.isTemplateFile("page.html"))
+		assert.False(t, engine.isTemplateFile("page.tmpl"))
+		assert.True(t, (&Engine{}).isTemplateFile("page.tmpl"))
+	})
+
+	t.Run("ThemeValidation", func(t *testing.T) {
+		fsys := htmlThemeFS("themes/default")
+		discovery := NewThemeDiscoveryWithFS(fsys, "themes", nil, DefaultLoadTemplateFS)
+		assert.NoError(t, discovery.ValidateTheme("themes/default"))
+
+		discovery.SetExtensions(".tmpl")
+		assert.Error(t, discovery.ValidateTheme("themes/default"))
+	})
+}
+
+// TestNormalizeExtensions 测试扩展名的规范化
+func TestNormalizeExtensions(t *testing.T) {
+	assert.Equal(t, []string{".html", ".tmpl"}, normalizeExtensions([]string{"html", "", ".tmpl", ".html"}))
+	assert.Nil(t, normalizeExtensions(nil))
+}
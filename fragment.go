@@ -0,0 +1,61 @@
+package template
+
+import (
+	"context"
+	"io"
+	"net/http"
+)
+
+// RenderFragment 只渲染页面集合中的指定块，如 layout.tmpl:pages/posts/list 中的 content，不包含外层布局
+func (en *Engine) RenderFragment(w io.Writer, kind, name, block string, data H, opts ...Option) error {
+	job, err := en.prepareFragment(context.Background(), kind, name, block, data, opts...)
+	if err != nil {
+		return err
+	}
+	return en.execute(w, job)
+}
+
+// HTMLFragment 只渲染页面集合中的指定块并写入HTTP响应，见 HTML
+func (en *Engine) HTMLFragment(w http.ResponseWriter, r *http.Request, kind, name, block string, data H, opts ...Option) error {
+	job, err := en.prepareFragment(requestContext(r), kind, name, block, data, opts...)
+	if err != nil {
+		return err
+	}
+	return en.writeHTML(w, r, job)
+}
+
+// HTMX 渲染页面并写入HTTP响应：请求带有 HX-Request 头时只渲染 FragmentBlock 指定的块（默认 content），
+// 否则渲染完整页面。hx-boost 发起的请求（HX-Boosted）需要完整页面，不做处理。
+func (en *Engine) HTMX(w http.ResponseWriter, r *http.Request, kind, name string, data H, opts ...Option) error {
+	// 同一地址会根据请求头返回不同内容
+	w.Header().Add("Vary", "HX-Request")
+
+	if !isFragmentRequest(r) {
+		return en.HTML(w, r, kind, name, data, opts...)
+	}
+
+	job, err := en.prepare(r.Context(), name, kind, data, opts...)
+	if err != nil {
+		return err
+	}
+	job.block = job.opt.FragmentBlock
+	return en.writeHTML(w, r, job)
+}
+
+// prepareFragment 准备只渲染指定块的渲染任务
+func (en *Engine) prepareFragment(ctx context.Context, kind, name, block string, data any, opts ...Option) (*renderJob, error) {
+	job, err := en.prepare(ctx, name, kind, data, opts...)
+	if err != nil {
+		return nil, err
+	}
+	job.block = block
+	return job, nil
+}
+
+// isFragmentRequest 判断是否为只需要页面片段的 HTMX 请求
+func isFragmentRequest(r *http.Request) bool {
+	if r == nil {
+		return false
+	}
+	return r.Header.Get("HX-Request") == "true" && r.Header.Get("HX-Boosted") != "true"
+}
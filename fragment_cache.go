@@ -0,0 +1,73 @@
+package template
+
+import (
+	"errors"
+	"html/template"
+	"time"
+)
+
+// fragmentCacheFuncName 模板中缓存片段的函数名称
+const fragmentCacheFuncName = "cache"
+
+// fragmentCacheFunc 模板中缓存片段的函数：{{ cache "sidebar" 300 "sidebar.tmpl" . }}
+type fragmentCacheFunc func(key string, ttl int, name string, data ...any) (template.HTML, error)
+
+// unboundCacheFunc 解析模板时使用的占位函数，发布渲染器快照时替换为绑定了模板集合的函数
+func unboundCacheFunc(key string, ttl int, name string, data ...any) (template.HTML, error) {
+	return "", errors.New("cache func is not bound to a template set")
+}
+
+// funcMap 返回加载模板使用的函数表，在调用方的函数之外加入 cache 函数（调用方已定义同名函数时不加入）。
+// 调用方的函数都经过 recoverFunc 包装，单次渲染设置 RecoverPanics 时同样能得到 *PanicError
+func (en *Engine) funcMap() FuncMap {
+	funcMap := make(FuncMap, len(en.FuncMap)+1)
+	for name, fn := range en.FuncMap {
+		funcMap[name] = recoverFunc(fn)
+	}
+	if _, ok := funcMap[fragmentCacheFuncName]; !ok {
+		funcMap[fragmentCacheFuncName] = unboundCacheFunc
+	}
+	return funcMap
+}
+
+// bindCacheFuncs 为快照中每个模板集合绑定 cache 函数，片段缓存按主题区分
+func (en *Engine) bindCacheFuncs(set *renderSet) {
+	if _, ok := en.FuncMap[fragmentCacheFuncName]; ok {
+		return
+	}
+	bind := func(theme string, render Render) {
+		for _, tmpl := range render {
+			tmpl.Funcs(template.FuncMap{fragmentCacheFuncName: en.cacheFunc(theme, tmpl)})
+		}
+	}
+	bind(set.theme, set.current)
+	for theme, render := range set.themes {
+		bind(theme, render)
+	}
+}
+
+// cacheFunc 创建绑定到模板集合的 cache 函数：执行集合中的 name 模板并按 key 缓存输出 ttl 秒，ttl 为 0 时不过期。
+// 缓存的片段带有 "fragment:<key>" 标签，可以通过 InvalidateCache 删除。
+func (en *Engine) cacheFunc(theme string, tmpl *template.Template) fragmentCacheFunc {
+	return func(key string, ttl int, name string, data ...any) (template.HTML, error) {
+		cacheKey := "fragment\x00" + theme + "\x00" + key
+		output, gen, ok := en.cache.get(cacheKey)
+		if ok {
+			return template.HTML(output), nil
+		}
+
+		var arg any
+		if len(data) > 0 {
+			arg = data[0]
+		}
+		buf := getBuffer()
+		defer putBuffer(buf)
+		if err := tmpl.ExecuteTemplate(buf, name, arg); err != nil {
+			return "", err
+		}
+
+		output = append([]byte(nil), buf.Bytes()...)
+		en.cache.set(gen, cacheKey, output, time.Duration(ttl)*time.Second, []string{"fragment:" + key})
+		return template.HTML(output), nil
+	}
+}
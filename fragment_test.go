@@ -0,0 +1,83 @@
+package template
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestRenderFragment 测试只渲染页面中的指定块
+func TestRenderFragment(t *testing.T) {
+	engine, err := NewEngine(createRenderTestStructure(t, true), DefaultLoadTemplate, nil)
+	require.NoError(t, err)
+	defer engine.Close()
+	require.NoError(t, engine.Init())
+
+	t.Run("ContentBlock", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, engine.RenderFragment(&buf, KindPage, "sample", "content", H{"title": "片段"}))
+
+		assert.Contains(t, buf.String(), "<h1>片段</h1>")
+		assert.NotContains(t, buf.String(), "<html>")
+		assert.NotContains(t, buf.String(), "<title>")
+	})
+
+	t.Run("MissingBlock", func(t *testing.T) {
+		var buf bytes.Buffer
+		err := engine.RenderFragment(&buf, KindPage, "sample", "sidebar", H{})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "sidebar")
+	})
+
+	t.Run("BufferedDoesNotFallback", func(t *testing.T) {
+		var buf bytes.Buffer
+		err := engine.RenderFragment(&buf, KindPage, "broken", "content", H{"title": "标题", "user": "guest"}, Buffered(true))
+		require.Error(t, err)
+		assert.Zero(t, buf.Len())
+	})
+}
+
+// TestHTMX 测试根据 HTMX 请求头选择渲染完整页面或片段
+func TestHTMX(t *testing.T) {
+	engine, err := NewEngine(createRenderTestStructure(t, true), DefaultLoadTemplate, nil)
+	require.NoError(t, err)
+	defer engine.Close()
+	require.NoError(t, engine.Init())
+
+	render := func(headers map[string]string, opts ...Option) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		rec := httptest.NewRecorder()
+		require.NoError(t, engine.HTMX(rec, req, KindPage, "sample", H{"title": "列表"}, opts...))
+		return rec
+	}
+
+	t.Run("FullPage", func(t *testing.T) {
+		rec := render(nil)
+		assert.Contains(t, rec.Body.String(), "<html>")
+		assert.Equal(t, "HX-Request", rec.Header().Get("Vary"))
+	})
+
+	t.Run("Fragment", func(t *testing.T) {
+		rec := render(map[string]string{"HX-Request": "true"})
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Contains(t, rec.Body.String(), "<h1>列表</h1>")
+		assert.NotContains(t, rec.Body.String(), "<html>")
+	})
+
+	t.Run("Boosted", func(t *testing.T) {
+		rec := render(map[string]string{"HX-Request": "true", "HX-Boosted": "true"})
+		assert.Contains(t, rec.Body.String(), "<html>")
+	})
+
+	t.Run("CustomBlock", func(t *testing.T) {
+		rec := render(map[string]string{"HX-Request": "true"}, FragmentBlock("header"))
+		assert.Equal(t, "<title>列表</title>", rec.Body.String())
+	})
+}
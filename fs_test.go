@@ -0,0 +1,248 @@
+package template
+
+import (
+	"bytes"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// themeMapFS 返回包含一个完整主题的内存文件系统，文件位于 dir 目录下
+func themeMapFS(fsys fstest.MapFS, dir, title string) fstest.MapFS {
+	files := map[string]string{
+		"layouts/layout.tmpl":          `<html><head>{{ template "header" . }}</head><body>{{ template "content" . }}</body></html>`,
+		"pages/posts/list/list.tmpl":   `{{ define "header" }}<title>` + title + `</title>{{ end }}{{ define "content" }}<h1>{{ .title }}</h1>{{ template "footer" }}{{ end }}`,
+		"pages/posts/broken/page.tmpl": "{{ define \"header\" }}{{ end }}\n{{ define \"content\" }}{{ .user.Name }}{{ end }}",
+		"partials/footer.tmpl":         `{{ define "footer" }}<footer>` + title + `</footer>{{ end }}`,
+		"singles/about.tmpl":           `<p>about ` + title + `</p>`,
+		"errors/404.tmpl":              `<p>404 ` + title + `</p>`,
+	}
+	for name, content := range files {
+		fsys[filepath.ToSlash(filepath.Join(dir, name))] = &fstest.MapFile{Data: []byte(content)}
+	}
+	return fsys
+}
+
+// TestLoadTemplateFS 测试从任意文件系统加载模板
+func TestLoadTemplateFS(t *testing.T) {
+	fsys := themeMapFS(fstest.MapFS{}, "site", "内存")
+
+	render, err := LoadTemplateFS(fsys, "site", nil)
+	require.NoError(t, err)
+	assert.True(t, render.HasTemplate("layout.tmpl:pages/posts/list"))
+	assert.True(t, render.HasTemplate("singles/about.tmpl"))
+	assert.True(t, render.HasTemplate("error/404.tmpl"))
+
+	var buf bytes.Buffer
+	require.NoError(t, render.Execute("layout.tmpl:pages/posts/list", &buf, H{"title": "文章"}))
+	assert.Equal(t, "<html><head><title>内存</title></head><body><h1>文章</h1><footer>内存</footer></body></html>", buf.String())
+
+	t.Run("ParseError", func(t *testing.T) {
+		broken := themeMapFS(fstest.MapFS{}, ".", "x")
+		broken["partials/bad.tmpl"] = &fstest.MapFile{Data: []byte("{{ if }}")}
+		_, err := LoadTemplateFS(broken, ".", nil)
+		var parseErr *ParseError
+		require.True(t, errors.As(err, &parseErr))
+		assert.Equal(t, "partials/bad.tmpl", parseErr.File)
+	})
+
+	t.Run("MatchesDiskLoader", func(t *testing.T) {
+		testDir := t.TempDir()
+		require.NoError(t, createLegacyStructure(testDir))
+
+		fromDisk, err := LoadTemplate(testDir, nil)
+		require.NoError(t, err)
+		fromFS, err := LoadTemplateFS(os.DirFS(testDir), ".", nil)
+		require.NoError(t, err)
+		for name := range fromDisk {
+			assert.True(t, fromFS.HasTemplate(name), name)
+		}
+		assert.Len(t, fromFS, len(fromDisk))
+	})
+}
+
+// TestNewEngineWithFS 测试使用任意文件系统创建引擎
+func TestNewEngineWithFS(t *testing.T) {
+	t.Run("Legacy", func(t *testing.T) {
+		engine, err := NewEngineWithFS(themeMapFS(fstest.MapFS{}, ".", "单主题"), DefaultLoadTemplateFS, nil)
+		require.NoError(t, err)
+		defer engine.Close()
+		require.NoError(t, engine.Init())
+
+		var buf bytes.Buffer
+		require.NoError(t, engine.RenderPage(&buf, "posts/list", H{"title": "文章"}))
+		assert.Contains(t, buf.String(), "<footer>单主题</footer>")
+
+		buf.Reset()
+		require.NoError(t, engine.RenderSingle(&buf, "about", nil))
+		assert.Equal(t, "<p>about 单主题</p>", buf.String())
+
+		assert.ErrorContains(t, engine.Watching(), "not supported for fs.FS templates")
+		assert.ErrorContains(t, engine.RestartWatching(), "not supported for fs.FS templates")
+	})
+
+	t.Run("TemplateErrorExcerpt", func(t *testing.T) {
+		engine, err := NewEngineWithFS(themeMapFS(fstest.MapFS{}, ".", "x"), DefaultLoadTemplateFS, nil)
+		require.NoError(t, err)
+		defer engine.Close()
+		require.NoError(t, engine.Init())
+
+		var buf bytes.Buffer
+		err = engine.RenderPage(&buf, "posts/broken", H{"user": "guest"})
+		var tmplErr *TemplateError
+		require.True(t, errors.As(err, &tmplErr))
+		assert.Equal(t, "pages/posts/broken/page.tmpl", tmplErr.File)
+		assert.Contains(t, tmplErr.Excerpt, "> 2 | ")
+	})
+
+	t.Run("MultiTheme", func(t *testing.T) {
+		fsys := fstest.MapFS{}
+		themeMapFS(fsys, "themes/light", "浅色")
+		themeMapFS(fsys, "themes/dark", "深色")
+		fsys["themes/dark/theme.json"] = &fstest.MapFile{Data: []byte(`{"display_name": "Dark", "version": "2.0.0"}`)}
+		sub, err := fs.Sub(fsys, "themes")
+		require.NoError(t, err)
+
+		engine, err := NewEngineWithFS(sub, DefaultLoadTemplateFS, nil, EnableMultiTheme(true), DefaultTheme("light"))
+		require.NoError(t, err)
+		defer engine.Close()
+		require.NoError(t, engine.Init())
+
+		assert.ElementsMatch(t, []string{"light", "dark"}, engine.GetAvailableThemes())
+		assert.Equal(t, "light", engine.GetCurrentTheme())
+		metadata, err := engine.GetThemeMetadata("dark")
+		require.NoError(t, err)
+		assert.Equal(t, "Dark", metadata.DisplayName)
+
+		var buf bytes.Buffer
+		require.NoError(t, engine.RenderPage(&buf, "posts/list", H{"title": "文章"}, SetTheme("dark")))
+		assert.Contains(t, buf.String(), "<footer>深色</footer>")
+
+		require.NoError(t, engine.SwitchTheme("dark"))
+		buf.Reset()
+		require.NoError(t, engine.RenderSingle(&buf, "about", nil))
+		assert.Equal(t, "<p>about 深色</p>", buf.String())
+
+		provider, ok := engine.GetThemeManager().(ThemeProvider)
+		require.True(t, ok)
+		theme, err := provider.GetTheme("dark")
+		require.NoError(t, err)
+		assert.True(t, theme.IsEmbedded)
+		assert.Equal(t, "dark", theme.Path)
+	})
+
+	t.Run("ManagerWithoutThemeRenders", func(t *testing.T) {
+		fsys := fstest.MapFS{}
+		themeMapFS(fsys, "light", "浅色")
+		themeMapFS(fsys, "dark", "深色")
+
+		engine, err := NewEngineWithFS(fsys, DefaultLoadTemplateFS, nil, EnableMultiTheme(true), DefaultTheme("light"))
+		require.NoError(t, err)
+		defer engine.Close()
+		require.NoError(t, engine.Init())
+
+		// 只实现 ThemeManager 的主题管理器只能使用当前主题渲染
+		engine.themeManager = struct{ ThemeManager }{engine.themeManager}
+		set, err := engine.managerRenders()
+		require.NoError(t, err)
+		engine.storeRenders(set)
+
+		var buf bytes.Buffer
+		require.NoError(t, engine.RenderPage(&buf, "posts/list", H{"title": "文章"}, SetTheme("light")))
+		assert.Contains(t, buf.String(), "<footer>浅色</footer>")
+		err = engine.RenderPage(&bytes.Buffer{}, "posts/list", nil, SetTheme("dark"))
+		var themeErr *ThemeError
+		require.True(t, errors.As(err, &themeErr))
+		assert.Equal(t, ErrThemeNotFound, themeErr.Type)
+	})
+}
+
+// TestThemeDiscoveryWithFS 测试基于 fs.FS 的主题发现
+func TestThemeDiscoveryWithFS(t *testing.T) {
+	fsys := fstest.MapFS{}
+	themeMapFS(fsys, "themes/default", "默认")
+	fsys["themes/empty/layouts/readme.txt"] = &fstest.MapFile{Data: []byte("not a template")}
+
+	discovery := NewThemeDiscoveryWithFS(fsys, "themes", nil, DefaultLoadTemplateFS)
+	mode, err := discovery.DetectMode()
+	require.NoError(t, err)
+	assert.Equal(t, ModeMultiTheme, mode)
+	assert.NoError(t, discovery.ValidateTheme("themes/default"))
+	assert.Error(t, discovery.ValidateTheme("themes/empty"))
+
+	manager := NewDefaultThemeManagerWithFS(fsys, "themes", nil, DefaultLoadTemplateFS)
+	require.NoError(t, manager.DiscoverThemes())
+	assert.Equal(t, []string{"default"}, manager.GetAvailableThemes())
+	assert.True(t, manager.GetRender().HasTemplate("layout.tmpl:pages/posts/list"))
+
+	t.Run("DiskPaths", func(t *testing.T) {
+		testDir := t.TempDir()
+		require.NoError(t, createThemeStructure(filepath.Join(testDir, "site", "default")))
+
+		// 磁盘目录通过以 baseDir 为根的 os.DirFS 访问，主题路径仍为操作系统路径
+		discovery := NewThemeDiscovery(filepath.Join(testDir, "site"), nil, DefaultLoadTemplate)
+		assert.Equal(t, "default", discovery.fsPath(filepath.Join(testDir, "site", "default")))
+		assert.NoError(t, discovery.ValidateTheme(filepath.Join(testDir, "site", "default")))
+		// baseDir 之外的路径不满足 fs.ValidPath
+		assert.Error(t, discovery.ValidateTheme(testDir))
+	})
+}
+
+// TestSharedParseTrees 测试共享解析的布局和局部页面在各模板集合中相互独立，且与依次解析的结果相同
+func TestSharedParseTrees(t *testing.T) {
+	fsys := fstest.MapFS{
+		"layouts/layout.tmpl":      {Data: []byte(`{{ template "content" . }}|{{ block "footer" . }}默认{{ end }}`)},
+		"partials/value.tmpl":      {Data: []byte(`{{ define "value" }}{{ . }}{{ end }}`)},
+		"partials/footer.tmpl":     {Data: []byte(`{{ define "footer" }}局部{{ end }}`)},
+		"pages/script/page.tmpl":   {Data: []byte(`{{ define "content" }}<script>var v = {{ template "value" .v }};</script>{{ end }}`)},
+		"pages/text/page.tmpl":     {Data: []byte(`{{ define "content" }}<p>{{ template "value" .v }}</p>{{ end }}`)},
+		"singles/about.tmpl":       {Data: []byte(`<b>{{ template "value" .v }}</b>`)},
+		"pages/override/page.tmpl": {Data: []byte(`{{ define "content" }}{{ end }}{{ define "footer" }}页面{{ end }}`)},
+	}
+	render, err := LoadTemplateFS(fsys, ".", nil)
+	require.NoError(t, err)
+
+	// 不同上下文中转义同一个局部页面互不影响
+	data := H{"v": "<b>"}
+	var buf bytes.Buffer
+	require.NoError(t, render.Execute("layout.tmpl:pages/script", &buf, data))
+	assert.Equal(t, `<script>var v = "\u003cb\u003e";</script>|局部`, buf.String())
+	buf.Reset()
+	require.NoError(t, render.Execute("layout.tmpl:pages/text", &buf, data))
+	assert.Equal(t, "<p>&lt;b&gt;</p>|局部", buf.String())
+	buf.Reset()
+	require.NoError(t, render.Execute("singles/about.tmpl", &buf, data))
+	assert.Equal(t, "<b>&lt;b&gt;</b>", buf.String())
+
+	// 页面中的定义覆盖局部页面，局部页面覆盖布局中的默认块
+	buf.Reset()
+	require.NoError(t, render.Execute("layout.tmpl:pages/override", &buf, nil))
+	assert.Equal(t, "|页面", buf.String())
+
+	t.Run("BrokenLayout", func(t *testing.T) {
+		broken := fstest.MapFS{
+			"layouts/layout.tmpl":  {Data: []byte(`{{ if }}`)},
+			"pages/a/page.tmpl":    {Data: []byte(`{{ define "content" }}{{ end }}`)},
+			"pages/b/page.tmpl":    {Data: []byte(`{{ define "content" }}{{ end }}`)},
+			"pages/c/page.tmpl":    {Data: []byte(`{{ end }}`)},
+			"partials/footer.tmpl": {Data: []byte(`{{ define "footer" }}{{ end }}`)},
+		}
+		_, err := LoadTemplateFS(broken, ".", nil)
+		var loadErrs LoadErrors
+		require.True(t, errors.As(err, &loadErrs))
+		require.Len(t, loadErrs, 2)
+		var files []string
+		for _, e := range loadErrs {
+			var parseErr *ParseError
+			require.True(t, errors.As(e, &parseErr))
+			files = append(files, parseErr.File)
+		}
+		assert.ElementsMatch(t, []string{"layouts/layout.tmpl", "pages/c/page.tmpl"}, files)
+	})
+}
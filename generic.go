@@ -0,0 +1,25 @@
+package template
+
+import (
+	"context"
+	"io"
+)
+
+// viewModelKey 泛型渲染方法中视图模型在模板数据中的键名
+const viewModelKey = "Data"
+
+// RenderPageT 使用类型化的视图模型渲染页面，模板中通过 {{ .Data.Field }} 访问视图模型，
+// 全局常量和变量仍通过 {{ .constant }}、{{ .variable }} 访问
+func RenderPageT[T any](en *Engine, w io.Writer, name string, data T, opts ...Option) error {
+	return en.render(context.Background(), w, name, KindPage, H{viewModelKey: data}, opts...)
+}
+
+// RenderSingleT 使用类型化的视图模型渲染单页面，见 RenderPageT
+func RenderSingleT[T any](en *Engine, w io.Writer, name string, data T, opts ...Option) error {
+	return en.render(context.Background(), w, name, KindSingle, H{viewModelKey: data}, opts...)
+}
+
+// RenderErrorT 使用类型化的视图模型渲染错误页面，见 RenderPageT
+func RenderErrorT[T any](en *Engine, w io.Writer, name string, data T, opts ...Option) error {
+	return en.render(context.Background(), w, name, KindError, H{viewModelKey: data}, opts...)
+}
@@ -0,0 +1,58 @@
+package template
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// postView 测试用的视图模型
+type postView struct {
+	Title string
+	Tags  []string
+}
+
+// Summary 视图模型的方法在模板中可用
+func (p postView) Summary() string {
+	return fmt.Sprintf("%s (%d)", p.Title, len(p.Tags))
+}
+
+// TestRenderGeneric 测试泛型渲染方法
+func TestRenderGeneric(t *testing.T) {
+	testDir := t.TempDir()
+	require.NoError(t, createLegacyStructure(testDir))
+
+	pageDir := filepath.Join(testDir, "pages", "post")
+	require.NoError(t, os.MkdirAll(pageDir, 0755))
+	pageContent := `{{ define "header" }}<title>{{ .Data.Title }}</title>{{ end }}
+{{ define "content" }}<h1>{{ .Data.Summary }}</h1>{{ range .Data.Tags }}<i>{{ . }}</i>{{ end }}<p>{{ .constant.site }}</p>{{ end }}`
+	require.NoError(t, os.WriteFile(filepath.Join(pageDir, "post.tmpl"), []byte(pageContent), 0644))
+	singleContent := `<h1>{{ .Data }}</h1>`
+	require.NoError(t, os.WriteFile(filepath.Join(testDir, "singles", "text.tmpl"), []byte(singleContent), 0644))
+
+	engine, err := NewEngine(testDir, DefaultLoadTemplate, nil, GlobalConstant(map[string]any{"site": "blog"}))
+	require.NoError(t, err)
+	defer engine.Close()
+	require.NoError(t, engine.Init())
+
+	t.Run("Page", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, RenderPageT(engine, &buf, "post", postView{Title: "泛型", Tags: []string{"go"}}))
+
+		assert.Contains(t, buf.String(), "<title>泛型</title>")
+		assert.Contains(t, buf.String(), "<h1>泛型 (1)</h1>")
+		assert.Contains(t, buf.String(), "<i>go</i>")
+		assert.Contains(t, buf.String(), "<p>blog</p>")
+	})
+
+	t.Run("Single", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, RenderSingleT(engine, &buf, "text", "纯文本"))
+		assert.Equal(t, "<h1>纯文本</h1>", buf.String())
+	})
+}
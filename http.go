@@ -0,0 +1,115 @@
+package template
+
+import (
+	"context"
+	"errors"
+	"net/http"
+)
+
+// contentTypeHTML HTML 响应的 Content-Type
+const contentTypeHTML = "text/html; charset=utf-8"
+
+// HTML 渲染模板并写入HTTP响应。
+// kind 为 KindPage、KindSingle 或 KindError；响应状态码由 StatusCode 选项指定，默认 200。
+// 启用 Buffered 时，只有渲染成功才写入状态码和页面；渲染失败且回退到错误页面时写入 500，
+// 错误页面也不可用时不写入任何内容，由调用方处理返回的错误。
+// 不使用缓冲时先确认模板存在，模板不存在时写入 404，块不存在时写入 500，再返回错误。
+// 渲染使用请求的上下文，见 RenderPageContext。启用 Negotiate 时根据 Accept 请求头返回 JSON 或 XML 格式的页面数据。
+func (en *Engine) HTML(w http.ResponseWriter, r *http.Request, kind, name string, data H, opts ...Option) error {
+	job, err := en.prepare(requestContext(r), name, kind, data, opts...)
+	if err != nil {
+		return err
+	}
+	return en.writeHTML(w, r, job)
+}
+
+// writeHTML 执行渲染任务并写入HTTP响应
+func (en *Engine) writeHTML(w http.ResponseWriter, r *http.Request, job *renderJob) error {
+	job.request = r
+	if job.opt.Negotiate {
+		// 同一地址会根据 Accept 请求头返回不同格式
+		w.Header().Add("Vary", "Accept")
+	}
+	if format := job.responseFormat(); format != formatHTML {
+		return en.writeData(w, job, format)
+	}
+	if output, ok := en.cachedOutput(job); ok {
+		writeHTMLHeader(w, job.opt.StatusCode)
+		_, err := w.Write(output)
+		return err
+	}
+	if err := job.beforeRender(); err != nil {
+		return err
+	}
+
+	if !job.buffered() {
+		// 写入状态码后无法再修改，先确认模板存在
+		if err := job.lookup(); err != nil {
+			writeHTMLHeader(w, lookupStatus(err))
+			return err
+		}
+		writeHTMLHeader(w, job.opt.StatusCode)
+		return job.execute(w)
+	}
+
+	buf := getBuffer()
+	defer putBuffer(buf)
+
+	fallback, err := en.executeWithFallback(buf, job)
+	switch {
+	case err == nil:
+		writeHTMLHeader(w, job.opt.StatusCode)
+	case fallback:
+		writeHTMLHeader(w, http.StatusInternalServerError)
+	default:
+		return err
+	}
+
+	if _, writeErr := buf.WriteTo(w); writeErr != nil && err == nil {
+		return writeErr
+	}
+	return err
+}
+
+// HTMLPage 渲染页面并写入HTTP响应，见 HTML
+func (en *Engine) HTMLPage(w http.ResponseWriter, r *http.Request, name string, data H, opts ...Option) error {
+	return en.HTML(w, r, KindPage, name, data, opts...)
+}
+
+// HTMLSingle 渲染单页并写入HTTP响应，见 HTML
+func (en *Engine) HTMLSingle(w http.ResponseWriter, r *http.Request, name string, data H, opts ...Option) error {
+	return en.HTML(w, r, KindSingle, name, data, opts...)
+}
+
+// HTMLError 渲染错误页面并写入HTTP响应，见 HTML
+func (en *Engine) HTMLError(w http.ResponseWriter, r *http.Request, name string, data H, opts ...Option) error {
+	return en.HTML(w, r, KindError, name, data, opts...)
+}
+
+// lookupStatus 模板查找失败时的响应状态码，模板不存在时为 404，其他情况为 500
+func lookupStatus(err error) int {
+	if errors.Is(err, ErrTemplateNotFound) {
+		return http.StatusNotFound
+	}
+	return http.StatusInternalServerError
+}
+
+// requestContext 返回请求的上下文，请求为空时返回 context.Background()
+func requestContext(r *http.Request) context.Context {
+	if r == nil {
+		return context.Background()
+	}
+	return r.Context()
+}
+
+// writeHTMLHeader 设置 Content-Type（调用方未设置时）并写入状态码
+func writeHTMLHeader(w http.ResponseWriter, statusCode int) {
+	header := w.Header()
+	if header.Get("Content-Type") == "" {
+		header.Set("Content-Type", contentTypeHTML)
+	}
+	if statusCode == 0 {
+		statusCode = http.StatusOK
+	}
+	w.WriteHeader(statusCode)
+}
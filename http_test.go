@@ -0,0 +1,77 @@
+package template
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestHTMLResponse 测试写入HTTP响应的渲染方法
+func TestHTMLResponse(t *testing.T) {
+	engine, err := NewEngine(createRenderTestStructure(t, true), DefaultLoadTemplate, nil)
+	require.NoError(t, err)
+	defer engine.Close()
+	require.NoError(t, engine.Init())
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+
+	t.Run("DefaultStatus", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		require.NoError(t, engine.HTMLPage(rec, req, "sample", H{"title": "首页"}))
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "text/html; charset=utf-8", rec.Header().Get("Content-Type"))
+		assert.Contains(t, rec.Body.String(), "<h1>首页</h1>")
+	})
+
+	t.Run("StatusCodeOption", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		require.NoError(t, engine.HTMLError(rec, req, "500", H{"title": "出错了"}, StatusCode(http.StatusServiceUnavailable)))
+
+		assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+		assert.Contains(t, rec.Body.String(), "出错了")
+	})
+
+	t.Run("KeepsContentType", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		rec.Header().Set("Content-Type", "application/xhtml+xml")
+		require.NoError(t, engine.HTML(rec, req, KindSingle, "sample", H{"title": "单页"}))
+
+		assert.Equal(t, "application/xhtml+xml", rec.Header().Get("Content-Type"))
+	})
+
+	t.Run("MissingTemplate", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		err := engine.HTMLPage(rec, req, "nope", nil)
+		assert.ErrorIs(t, err, ErrTemplateNotFound)
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+		assert.Zero(t, rec.Body.Len())
+
+		rec = httptest.NewRecorder()
+		err = engine.HTMLFragment(rec, req, KindPage, "sample", "missing", nil)
+		require.Error(t, err)
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	})
+
+	t.Run("BufferedFallback", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		err := engine.HTMLPage(rec, req, "broken", H{"title": "标题", "user": "guest"}, Buffered(true), StatusCode(http.StatusCreated))
+		require.Error(t, err)
+
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+		assert.Equal(t, "<h1>500 Internal Server Error</h1>", rec.Body.String())
+	})
+
+	t.Run("BufferedWithoutFallback", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		err := engine.HTMLPage(rec, req, "broken", H{"title": "标题", "user": "guest"}, Buffered(true), FallbackError(""))
+		require.Error(t, err)
+
+		// 未写入任何内容，调用方仍可以自行输出错误
+		assert.Empty(t, rec.Header().Get("Content-Type"))
+		assert.Zero(t, rec.Body.Len())
+	})
+}
@@ -9,8 +9,11 @@ import (
 	"net/url"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
 
+	"github.com/fsnotify/fsnotify"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -34,7 +37,7 @@ func TestMultiThemeIntegration(t *testing.T) {
 	require.NoError(t, err)
 	defer engine.Close()
 
-	engine.Init()
+	require.NoError(t, engine.Init())
 
 	// 测试基本功能
 	t.Run("BasicMultiThemeFunctionality", func(t *testing.T) {
@@ -282,7 +285,7 @@ func testBackwardCompatibility(t *testing.T, baseDir string) {
 		require.NoError(t, err)
 		defer engine.Close()
 
-		engine.Init()
+		require.NoError(t, engine.Init())
 
 		// 在传统模式下，应该只有一个默认主题
 		themes := engine.GetAvailableThemes()
@@ -315,7 +318,7 @@ func testBackwardCompatibility(t *testing.T, baseDir string) {
 		require.NoError(t, err)
 		defer engine.Close()
 
-		engine.Init()
+		require.NoError(t, engine.Init())
 
 		// 应该自动检测为传统模式
 		themes := engine.GetAvailableThemes()
@@ -457,3 +460,128 @@ func createThemeDir(t *testing.T, themeDir, themeName, primaryColor, backgroundC
 	err = os.WriteFile(filepath.Join(themeDir, "errors", "404.tmpl"), []byte(errorContent), 0644)
 	require.NoError(t, err)
 }
+
+// TestPerRequestThemeSelection 测试按请求选择主题而不修改引擎当前主题
+func TestPerRequestThemeSelection(t *testing.T) {
+	testDir := setupMultiThemeTestDir(t)
+	defer os.RemoveAll(testDir)
+
+	engine, err := NewEngine(testDir, DefaultLoadTemplate, nil,
+		EnableMultiTheme(true),
+		DefaultTheme("default"),
+	)
+	require.NoError(t, err)
+	defer engine.Close()
+
+	require.NoError(t, engine.Init())
+	require.Equal(t, "default", engine.GetCurrentTheme())
+
+	t.Run("RenderWithTheme", func(t *testing.T) {
+		var darkBuf, defaultBuf bytes.Buffer
+		require.NoError(t, engine.RenderPage(&darkBuf, "test", H{"title": "按请求主题"}, SetTheme("dark")))
+		require.NoError(t, engine.RenderPage(&defaultBuf, "test", H{"title": "按请求主题"}))
+
+		assert.Contains(t, darkBuf.String(), "background: #1a1a1a")
+		assert.Contains(t, defaultBuf.String(), "background: #ffffff")
+
+		// 引擎的当前主题不应被修改
+		assert.Equal(t, "default", engine.GetCurrentTheme())
+	})
+
+	t.Run("SingleAndErrorWithTheme", func(t *testing.T) {
+		var singleBuf, errorBuf bytes.Buffer
+		require.NoError(t, engine.RenderSingle(&singleBuf, "login", H{"title": "登录"}, SetTheme("dark")))
+		require.NoError(t, engine.RenderError(&errorBuf, "404", H{"title": "未找到"}, SetTheme("dark")))
+
+		assert.Contains(t, singleBuf.String(), "dark主题")
+		assert.Contains(t, errorBuf.String(), "dark主题")
+	})
+
+	t.Run("UnknownTheme", func(t *testing.T) {
+		var buf bytes.Buffer
+		err := engine.RenderPage(&buf, "test", H{}, SetTheme("nonexistent"))
+		require.Error(t, err)
+
+		var themeErr *ThemeError
+		require.ErrorAs(t, err, &themeErr)
+		assert.Equal(t, ErrThemeNotFound, themeErr.Type)
+	})
+
+	t.Run("ConcurrentThemes", func(t *testing.T) {
+		var wg sync.WaitGroup
+		errs := make(chan error, 20)
+		for i := 0; i < 20; i++ {
+			theme, want := "default", "background: #ffffff"
+			if i%2 == 0 {
+				theme, want = "dark", "background: #1a1a1a"
+			}
+			wg.Add(1)
+			go func(theme, want string) {
+				defer wg.Done()
+				var buf bytes.Buffer
+				if err := engine.RenderPage(&buf, "test", H{"title": theme}, SetTheme(theme)); err != nil {
+					errs <- err
+					return
+				}
+				if !strings.Contains(buf.String(), want) {
+					errs <- fmt.Errorf("theme %s rendered unexpected output", theme)
+				}
+			}(theme, want)
+		}
+		wg.Wait()
+		close(errs)
+		for err := range errs {
+			t.Error(err)
+		}
+	})
+
+	t.Run("ReloadNonCurrentTheme", func(t *testing.T) {
+		// 所有主题的目录都被监听
+		assert.ElementsMatch(t, []string{filepath.Join(testDir, "dark"), filepath.Join(testDir, "default")}, engine.getWatchDirectories())
+
+		file := filepath.Join(testDir, "dark", "singles", "login.tmpl")
+		require.NoError(t, os.WriteFile(file, []byte("<p>新的 dark 登录页</p>"), 0644))
+		engine.handleFileEvent(engine.watcher, fsnotify.Event{Name: file, Op: fsnotify.Write})
+
+		var buf bytes.Buffer
+		require.NoError(t, engine.RenderSingle(&buf, "login", nil, SetTheme("dark")))
+		assert.Equal(t, "<p>新的 dark 登录页</p>", buf.String())
+		assert.Equal(t, "default", engine.GetCurrentTheme())
+
+		// 其他主题中的模板有误时保留该主题之前的渲染器并发布错误
+		require.NoError(t, os.WriteFile(file, []byte("{{ if }}"), 0644))
+		engine.handleFileEvent(engine.watcher, fsnotify.Event{Name: file, Op: fsnotify.Write})
+		select {
+		case err := <-engine.Errors:
+			assert.Contains(t, err.Error(), "failed to reload theme dark")
+		default:
+			t.Fatal("expected reload error")
+		}
+		buf.Reset()
+		require.NoError(t, engine.RenderSingle(&buf, "login", nil, SetTheme("dark")))
+		assert.Equal(t, "<p>新的 dark 登录页</p>", buf.String())
+	})
+}
+
+// TestBrokenNonDefaultTheme 测试未使用的主题模板有误时 Init 同样返回错误
+func TestBrokenNonDefaultTheme(t *testing.T) {
+	testDir := setupMultiThemeTestDir(t)
+	defer os.RemoveAll(testDir)
+	require.NoError(t, os.WriteFile(filepath.Join(testDir, "dark", "singles", "login.tmpl"), []byte("{{ if }}"), 0644))
+
+	engine, err := NewEngine(testDir, DefaultLoadTemplate, nil,
+		EnableMultiTheme(true),
+		DefaultTheme("default"),
+	)
+	require.NoError(t, err)
+	defer engine.Close()
+
+	err = engine.Init()
+	var parseErr *ParseError
+	require.ErrorAs(t, err, &parseErr)
+	assert.Equal(t, filepath.Join(testDir, "dark", "singles", "login.tmpl"), parseErr.File)
+
+	var themeErr *ThemeError
+	require.ErrorAs(t, err, &themeErr)
+	assert.Equal(t, "dark", themeErr.Theme)
+}
@@ -0,0 +1,128 @@
+package template
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+)
+
+// layoutManifestName 页面目录中声明可用布局的清单文件名称
+const layoutManifestName = "layouts.json"
+
+// layoutManifest 布局清单，如 {"layouts": ["admin.tmpl", "pjax_layout.tmpl"]}
+type layoutManifest struct {
+	Layouts []string `json:"layouts"`
+}
+
+// pageLayouts 决定每个页面目录与哪些布局组合，代替页面和布局的完整笛卡尔积：
+//   - 页面目录及其上级目录中最近的 layouts.json 声明的布局
+//   - 以 pages 下一级目录命名的专用布局，如 layouts/admin.tmpl 只用于 pages/admin/**
+//   - 其余的通用布局，error 和 single 布局只用于错误页面和单页文件夹
+type pageLayouts struct {
+	general   []string            // 通用布局
+	sections  map[string][]string // 专用布局，按 pages 下一级目录名称
+	manifests map[string][]string // 清单声明的布局，按页面目录相对 pages 的路径，pages 目录本身为 ""
+}
+
+// newPageLayouts 对 layouts 中的布局分类并读取 pageDirs 中的布局清单，有误的清单记录错误后忽略
+func newPageLayouts(src templateSource, basePagePath string, layouts, pageDirs []string) (*pageLayouts, error) {
+	pl := &pageLayouts{
+		sections:  make(map[string][]string),
+		manifests: make(map[string][]string),
+	}
+	for _, layout := range layouts {
+		stem := templateStem(path.Base(layout))
+		switch {
+		case stem == "error" || stem == "single":
+			continue
+		case isDir(src.fsys, path.Join(basePagePath, stem)):
+			pl.sections[stem] = append(pl.sections[stem], layout)
+		default:
+			pl.general = append(pl.general, layout)
+		}
+	}
+
+	var errs LoadErrors
+	for _, dir := range append([]string{basePagePath}, pageDirs...) {
+		allowed, err := readLayoutManifest(src, path.Join(dir, layoutManifestName), layouts)
+		if err != nil {
+			errs.add(err)
+			continue
+		}
+		if allowed != nil {
+			pl.manifests[pageRel(basePagePath, dir)] = allowed
+		}
+	}
+	return pl, errs.err()
+}
+
+// layoutsFor 返回相对 pages 的路径为 pageName 的页面目录使用的布局
+func (pl *pageLayouts) layoutsFor(pageName string) []string {
+	for dir := pageName; ; dir = path.Dir(dir) {
+		if dir == "." {
+			dir = ""
+		}
+		if allowed, ok := pl.manifests[dir]; ok {
+			return allowed
+		}
+		if dir == "" {
+			break
+		}
+	}
+	section, _, _ := strings.Cut(pageName, "/")
+	if layouts, ok := pl.sections[section]; ok {
+		return layouts
+	}
+	return pl.general
+}
+
+// readLayoutManifest 读取布局清单并返回其中布局的路径，清单不存在时返回 nil，声明了不存在的布局时返回错误
+func readLayoutManifest(src templateSource, file string, layouts []string) ([]string, error) {
+	data, err := fs.ReadFile(src.fsys, file)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var manifest layoutManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("invalid layout manifest %s: %w", src.filePath(file), err)
+	}
+	allowed := make([]string, 0, len(manifest.Layouts))
+	for _, name := range manifest.Layouts {
+		layout, ok := findLayoutFile(layouts, name)
+		if !ok {
+			return nil, fmt.Errorf("layout manifest %s: layout %s not found", src.filePath(file), name)
+		}
+		allowed = append(allowed, layout)
+	}
+	return allowed, nil
+}
+
+// findLayoutFile 在 layouts 中查找文件名为 name 的布局
+func findLayoutFile(layouts []string, name string) (string, bool) {
+	for _, layout := range layouts {
+		if path.Base(layout) == name {
+			return layout, true
+		}
+	}
+	return "", false
+}
+
+// pageRel 返回页面目录相对 pages 目录的路径，pages 目录本身为 ""
+func pageRel(basePagePath, dir string) string {
+	if dir == basePagePath {
+		return ""
+	}
+	return strings.TrimPrefix(dir, basePagePath+"/")
+}
+
+// isDir 检查 fsys 中的路径是否为目录
+func isDir(fsys fs.FS, name string) bool {
+	info, err := fs.Stat(fsys, name)
+	return err == nil && info.IsDir()
+}
@@ -0,0 +1,92 @@
+package template
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// layoutsFS 返回包含通用布局、专用布局和布局清单的内存文件系统
+func layoutsFS() fstest.MapFS {
+	page := &fstest.MapFile{Data: []byte(`{{ define "content" }}{{ .title }}{{ end }}`)}
+	return fstest.MapFS{
+		"layouts/layout.tmpl":          {Data: []byte(`<main>{{ template "content" . }}</main>`)},
+		"layouts/pjax.tmpl":            {Data: []byte(`{{ template "content" . }}`)},
+		"layouts/admin.tmpl":           {Data: []byte(`<admin>{{ template "content" . }}</admin>`)},
+		"layouts/single.tmpl":          {Data: []byte(`<single>{{ template "content" . }}</single>`)},
+		"layouts/error.tmpl":           {Data: []byte(`<error>{{ template "content" . }}</error>`)},
+		"pages/posts/list/list.tmpl":   page,
+		"pages/admin/users/page.tmpl":  page,
+		"pages/docs/layouts.json":      {Data: []byte(`{"layouts": ["pjax.tmpl"]}`)},
+		"pages/docs/intro/page.tmpl":   page,
+		"pages/docs/api/ref/page.tmpl": page,
+		"errors/500/500.tmpl":          page,
+		"singles/about/about.tmpl":     page,
+	}
+}
+
+// TestPageLayouts 测试页面只与清单声明的、专用的或通用的布局组合
+func TestPageLayouts(t *testing.T) {
+	render, err := LoadTemplateFS(layoutsFS(), ".", nil)
+	require.NoError(t, err)
+
+	expected := []string{
+		// 通用布局
+		"layout.tmpl:pages/posts/list",
+		"pjax.tmpl:pages/posts/list",
+		// layouts/admin.tmpl 只用于 pages/admin/**
+		"admin.tmpl:pages/admin/users",
+		// pages/docs/layouts.json 作用于 pages/docs 及其子目录
+		"pjax.tmpl:pages/docs/intro",
+		"pjax.tmpl:pages/docs/api/ref",
+		// error 和 single 布局只用于错误页面和单页文件夹
+		"error.tmpl:error/500",
+		"single.tmpl:singles/about",
+	}
+	var names []string
+	for name := range render {
+		names = append(names, name)
+	}
+	assert.ElementsMatch(t, expected, names)
+
+	t.Run("SectionLayoutName", func(t *testing.T) {
+		engine, err := NewEngineWithFS(layoutsFS(), DefaultLoadTemplateFS, nil)
+		require.NoError(t, err)
+		defer engine.Close()
+		require.NoError(t, engine.Init())
+
+		var buf bytes.Buffer
+		require.NoError(t, engine.RenderPage(&buf, "admin/users", H{"title": "用户"}))
+		assert.Equal(t, "<admin>用户</admin>", buf.String())
+
+		// 渲染时指定了布局则不回退到专用布局
+		err = engine.RenderPage(&bytes.Buffer{}, "admin/users", nil, Layout("pjax.tmpl"))
+		assert.ErrorIs(t, err, ErrTemplateNotFound)
+
+		buf.Reset()
+		require.NoError(t, engine.RenderPage(&buf, "docs/intro", H{"title": "文档"}, Layout("pjax.tmpl")))
+		assert.Equal(t, "文档", buf.String())
+	})
+
+	t.Run("Watching", func(t *testing.T) {
+		engine := &Engine{}
+		assert.True(t, engine.shouldReloadForFile("pages/docs/layouts.json"))
+		assert.False(t, engine.shouldReloadForFile("pages/docs/notes.json"))
+	})
+
+	t.Run("InvalidManifest", func(t *testing.T) {
+		fsys := layoutsFS()
+		fsys["pages/docs/layouts.json"] = &fstest.MapFile{Data: []byte(`{"layouts": ["missing.tmpl"]}`)}
+		fsys["pages/posts/layouts.json"] = &fstest.MapFile{Data: []byte(`["layout.tmpl"]`)}
+		_, err := LoadTemplateFS(fsys, ".", nil)
+		var loadErrs LoadErrors
+		require.True(t, errors.As(err, &loadErrs))
+		require.Len(t, loadErrs, 2)
+		assert.Contains(t, err.Error(), "layout manifest pages/docs/layouts.json: layout missing.tmpl not found")
+		assert.Contains(t, err.Error(), "invalid layout manifest pages/posts/layouts.json")
+	})
+}
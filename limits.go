@@ -0,0 +1,114 @@
+package template
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"runtime/debug"
+)
+
+// ErrOutputLimitExceeded 渲染输出超过了 MaxOutputSize 限制
+var ErrOutputLimitExceeded = errors.New("template: output size limit exceeded")
+
+// ErrExecutionTimeout 模板执行超过了 MaxExecutionTime 限制，同时满足 errors.Is(err, context.DeadlineExceeded)
+var ErrExecutionTimeout = fmt.Errorf("template: execution time limit exceeded: %w", context.DeadlineExceeded)
+
+// PanicError 启用 RecoverPanics 时，模板执行过程中的 panic 被转换为此错误
+type PanicError struct {
+	Template string // 执行的模板名称
+	Value    any    // panic 的值
+	Stack    []byte // panic 时的调用栈
+}
+
+// Error 实现error接口
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("template: panic while executing %s: %v", e.Template, e.Value)
+}
+
+// Unwrap panic 的值为 error 时返回该错误
+func (e *PanicError) Unwrap() error {
+	err, _ := e.Value.(error)
+	return err
+}
+
+// limitWriter 限制写入的总字节数，超出时返回 ErrOutputLimitExceeded 使模板执行中止
+type limitWriter struct {
+	w         io.Writer
+	remaining int64
+}
+
+// Write 实现 io.Writer
+func (lw *limitWriter) Write(p []byte) (int, error) {
+	if int64(len(p)) > lw.remaining {
+		lw.remaining = 0
+		return 0, ErrOutputLimitExceeded
+	}
+	n, err := lw.w.Write(p)
+	lw.remaining -= int64(n)
+	return n, err
+}
+
+// recoverPanic 将模板执行中的 panic 转换为 *PanicError
+func (job *renderJob) recoverPanic(err *error) {
+	if r := recover(); r != nil {
+		*err = &PanicError{Template: job.tmplName, Value: r, Stack: debug.Stack()}
+	}
+}
+
+// funcPanic 模板函数中的 panic，由 recoverFunc 产生。text/template 将其作为函数返回的错误处理，
+// 错误信息与未包装时相同；启用了 RecoverPanics 的渲染在执行后为其关联 *PanicError
+type funcPanic struct {
+	value    any
+	stack    []byte
+	panicErr *PanicError
+}
+
+// Error 实现error接口，与 text/template 恢复未包装函数的 panic 时的错误信息一致
+func (p *funcPanic) Error() string {
+	return fmt.Sprint(p.value)
+}
+
+// Unwrap 返回关联的 *PanicError 和 panic 的值中的错误
+func (p *funcPanic) Unwrap() []error {
+	var errs []error
+	if p.panicErr != nil {
+		errs = append(errs, p.panicErr)
+	}
+	if err, ok := p.value.(error); ok {
+		errs = append(errs, err)
+	}
+	return errs
+}
+
+// recoverFuncPanic 启用 RecoverPanics 时，为执行错误中的模板函数 panic 关联 *PanicError
+func (job *renderJob) recoverFuncPanic(err error) {
+	var p *funcPanic
+	if job.opt.RecoverPanics && errors.As(err, &p) {
+		p.panicErr = &PanicError{Template: job.tmplName, Value: p.value, Stack: p.stack}
+	}
+}
+
+// recoverFunc 包装模板函数，函数中的 panic 转换为 *funcPanic 后重新 panic。
+// text/template 会恢复函数中的 panic 并作为执行错误返回，是否转换为 *PanicError 由每次渲染的选项决定
+func recoverFunc(fn any) any {
+	v := reflect.ValueOf(fn)
+	if v.Kind() != reflect.Func {
+		return fn
+	}
+	return reflect.MakeFunc(v.Type(), func(args []reflect.Value) []reflect.Value {
+		defer func() {
+			if r := recover(); r != nil {
+				if _, ok := r.(*funcPanic); ok {
+					panic(r)
+				}
+				panic(&funcPanic{value: r, stack: debug.Stack()})
+			}
+		}()
+		if v.Type().IsVariadic() {
+			return v.CallSlice(args)
+		}
+		return v.Call(args)
+	}).Interface()
+}
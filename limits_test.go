@@ -0,0 +1,145 @@
+package template
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// panicWriter 写入时 panic 的 io.Writer
+type panicWriter struct{}
+
+func (panicWriter) Write(p []byte) (int, error) {
+	panic("connection hijacked")
+}
+
+// TestExecutionLimits 测试执行保护选项
+func TestExecutionLimits(t *testing.T) {
+	testDir := createRenderTestStructure(t, true)
+	pageDir := filepath.Join(testDir, "pages", "slow")
+	require.NoError(t, os.MkdirAll(pageDir, 0755))
+	pageContent := `{{ define "header" }}<title>{{ .title }}</title>{{ end }}
+{{ define "content" }}{{ range .items }}<p>{{ slow }}</p>{{ end }}{{ end }}`
+	require.NoError(t, os.WriteFile(filepath.Join(pageDir, "slow.tmpl"), []byte(pageContent), 0644))
+
+	funcMap := FuncMap{
+		"slow": func() string {
+			time.Sleep(20 * time.Millisecond)
+			return "done"
+		},
+	}
+	engine, err := NewEngine(testDir, DefaultLoadTemplate, funcMap)
+	require.NoError(t, err)
+	defer engine.Close()
+	require.NoError(t, engine.Init())
+
+	t.Run("RecoverPanics", func(t *testing.T) {
+		err := engine.RenderPage(panicWriter{}, "sample", H{"title": "panic"}, RecoverPanics(true))
+		var panicErr *PanicError
+		require.True(t, errors.As(err, &panicErr))
+		assert.Equal(t, "connection hijacked", panicErr.Value)
+		assert.Equal(t, "layout.tmpl:pages/sample", panicErr.Template)
+		assert.NotEmpty(t, panicErr.Stack)
+
+		assert.Panics(t, func() {
+			engine.RenderPage(panicWriter{}, "sample", H{"title": "panic"})
+		})
+	})
+
+	t.Run("MaxOutputSize", func(t *testing.T) {
+		var buf bytes.Buffer
+		err := engine.RenderPage(&buf, "sample", H{"title": "很长的标题"}, MaxOutputSize(32))
+		assert.ErrorIs(t, err, ErrOutputLimitExceeded)
+		assert.LessOrEqual(t, buf.Len(), 32)
+
+		buf.Reset()
+		require.NoError(t, engine.RenderPage(&buf, "sample", H{"title": "标题"}, MaxOutputSize(1<<20)))
+	})
+
+	t.Run("MaxOutputSizeBuffered", func(t *testing.T) {
+		var buf bytes.Buffer
+		err := engine.RenderPage(&buf, "sample", H{"title": "标题"}, MaxOutputSize(64), Buffered(true))
+		assert.ErrorIs(t, err, ErrOutputLimitExceeded)
+		// 回退到错误页面
+		assert.Equal(t, "<h1>500 Internal Server Error</h1>", buf.String())
+
+		// 错误页面同样受输出大小的限制
+		buf.Reset()
+		err = engine.RenderPage(&buf, "sample", H{"title": "标题"}, MaxOutputSize(16), Buffered(true))
+		assert.ErrorIs(t, err, ErrOutputLimitExceeded)
+		assert.Empty(t, buf.String())
+	})
+
+	t.Run("MaxExecutionTime", func(t *testing.T) {
+		var buf bytes.Buffer
+		items := make([]int, 10)
+		start := time.Now()
+		err := engine.RenderPage(&buf, "slow", H{"title": "慢", "items": items}, MaxExecutionTime(30*time.Millisecond))
+		assert.ErrorIs(t, err, ErrExecutionTimeout)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.Less(t, time.Since(start), 150*time.Millisecond)
+	})
+
+	t.Run("CallerDeadlineIsNotExecutionTimeout", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Millisecond)
+		defer cancel()
+
+		var buf bytes.Buffer
+		err := engine.RenderPageContext(ctx, &buf, "slow", H{"title": "慢", "items": make([]int, 10)}, MaxExecutionTime(time.Minute))
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.NotErrorIs(t, err, ErrExecutionTimeout)
+	})
+}
+
+// TestRecoverFuncPanics 测试启用 RecoverPanics 时模板函数中的 panic 返回 *PanicError
+func TestRecoverFuncPanics(t *testing.T) {
+	testDir := createRenderTestStructure(t, true)
+	pageDir := filepath.Join(testDir, "pages", "boom")
+	require.NoError(t, os.MkdirAll(pageDir, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(pageDir, "boom.tmpl"),
+		[]byte(`{{ define "header" }}{{ end }}{{ define "content" }}{{ join "a" "b" }}{{ boom }}{{ end }}`), 0644))
+
+	funcMap := FuncMap{
+		"boom": func() string { panic("kaboom") },
+		"join": func(parts ...string) string { return strings.Join(parts, ",") },
+	}
+	newEngine := func(opts ...Option) *Engine {
+		engine, err := NewEngine(testDir, DefaultLoadTemplate, funcMap, opts...)
+		require.NoError(t, err)
+		t.Cleanup(func() { engine.Close() })
+		require.NoError(t, engine.Init())
+		return engine
+	}
+
+	var buf bytes.Buffer
+	err := newEngine(RecoverPanics(true)).RenderPage(&buf, "boom", nil)
+	var panicErr *PanicError
+	require.True(t, errors.As(err, &panicErr))
+	assert.Equal(t, "kaboom", panicErr.Value)
+	assert.Equal(t, "layout.tmpl:pages/boom", panicErr.Template)
+	assert.NotEmpty(t, panicErr.Stack)
+	var tmplErr *TemplateError
+	assert.True(t, errors.As(err, &tmplErr))
+	assert.Contains(t, buf.String(), "a,b")
+
+	// 未启用时与标准库的执行错误一致
+	engine := newEngine()
+	err = engine.RenderPage(&bytes.Buffer{}, "boom", nil)
+	require.Error(t, err)
+	assert.False(t, errors.As(err, &panicErr))
+	assert.Contains(t, err.Error(), "error calling boom: kaboom")
+
+	// 单次渲染启用时同样返回 *PanicError
+	err = engine.RenderPage(&bytes.Buffer{}, "boom", nil, RecoverPanics(true))
+	require.True(t, errors.As(err, &panicErr))
+	assert.Equal(t, "kaboom", panicErr.Value)
+	assert.Equal(t, "layout.tmpl:pages/boom", panicErr.Template)
+}
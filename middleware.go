@@ -0,0 +1,122 @@
+package template
+
+import (
+	"context"
+	"net/http"
+)
+
+// RenderInfo 渲染中间件可以访问的本次渲染信息
+type RenderInfo struct {
+	Context  context.Context // 渲染上下文
+	Request  *http.Request   // HTTP 方法传入的请求，其他渲染方法为 nil
+	Kind     string          // 渲染类型：KindPage、KindSingle 或 KindError
+	Name     string          // 渲染时传入的名称，如 posts/list
+	Template string          // 解析后的模板名称，如 layout.tmpl:pages/posts/list
+	Theme    string          // 本次渲染使用的主题，传统模式下为空
+	Block    string          // 只渲染的块名称，渲染整个模板时为空
+	Data     H               // 模板数据，为引擎持有的副本，前置钩子可以直接修改
+}
+
+// BeforeRenderFunc 渲染前执行的钩子，可以向模板数据中注入数据；返回错误时中止渲染
+type BeforeRenderFunc func(info *RenderInfo) error
+
+// AfterRenderFunc 渲染后执行的过滤器，接收完整的输出并返回转换后的输出；返回错误时不输出任何内容
+type AfterRenderFunc func(info *RenderInfo, output []byte) ([]byte, error)
+
+// Middleware 渲染中间件，由 BeforeRender 或 AfterRender 创建
+type Middleware struct {
+	before BeforeRenderFunc
+	after  AfterRenderFunc
+}
+
+// BeforeRender 创建渲染前执行的中间件
+func BeforeRender(fn BeforeRenderFunc) Middleware {
+	return Middleware{before: fn}
+}
+
+// AfterRender 创建渲染后执行的中间件。
+// 使用后置过滤器时渲染总是先输出到缓冲区，相当于启用了 Buffered。
+func AfterRender(fn AfterRenderFunc) Middleware {
+	return Middleware{after: fn}
+}
+
+// renderHooks 不可变的中间件快照，发布后不再修改
+type renderHooks struct {
+	before []BeforeRenderFunc
+	after  []AfterRenderFunc
+}
+
+// Use 添加渲染中间件，按添加顺序执行。可以与渲染并发调用，已开始的渲染不受影响。
+func (en *Engine) Use(middlewares ...Middleware) {
+	en.mu.Lock()
+	defer en.mu.Unlock()
+
+	hooks := &renderHooks{}
+	if old := en.hooks.Load(); old != nil {
+		hooks.before = append(hooks.before, old.before...)
+		hooks.after = append(hooks.after, old.after...)
+	}
+	for _, m := range middlewares {
+		if m.before != nil {
+			hooks.before = append(hooks.before, m.before)
+		}
+		if m.after != nil {
+			hooks.after = append(hooks.after, m.after)
+		}
+	}
+	en.hooks.Store(hooks)
+}
+
+// renderInfo 返回本次渲染的信息，首次调用时创建
+func (job *renderJob) renderInfo() *RenderInfo {
+	if job.info == nil {
+		job.info = &RenderInfo{
+			Context:  job.ctx,
+			Request:  job.request,
+			Kind:     job.typ,
+			Name:     job.name,
+			Template: job.tmplName,
+			Theme:    job.theme,
+			Block:    job.block,
+			Data:     job.data,
+		}
+	}
+	return job.info
+}
+
+// beforeRender 补充错误页面数据后执行前置钩子
+func (job *renderJob) beforeRender() error {
+	job.addErrorData()
+	if job.hooks == nil || len(job.hooks.before) == 0 {
+		return nil
+	}
+	info := job.renderInfo()
+	for _, fn := range job.hooks.before {
+		if err := fn(info); err != nil {
+			return err
+		}
+	}
+	// 钩子可能替换了整个数据
+	if info.Data == nil {
+		info.Data = H{}
+	}
+	job.data = info.Data
+	return nil
+}
+
+// afterRender 依次执行后置过滤器
+func (job *renderJob) afterRender(output []byte) ([]byte, error) {
+	info := job.renderInfo()
+	for _, fn := range job.hooks.after {
+		var err error
+		if output, err = fn(info, output); err != nil {
+			return nil, err
+		}
+	}
+	return output, nil
+}
+
+// hasFilters 是否有后置过滤器
+func (job *renderJob) hasFilters() bool {
+	return job.hooks != nil && len(job.hooks.after) > 0
+}
@@ -0,0 +1,112 @@
+package template
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestMiddleware 测试渲染中间件
+func TestMiddleware(t *testing.T) {
+	newEngine := func(t *testing.T) *Engine {
+		testDir := t.TempDir()
+		require.NoError(t, createLegacyStructure(testDir))
+		pageDir := filepath.Join(testDir, "pages", "account")
+		require.NoError(t, os.MkdirAll(pageDir, 0755))
+		pageContent := `{{ define "header" }}<title>{{ .title }}</title>{{ end }}
+{{ define "content" }}<p>{{ .user }}</p>{{ end }}`
+		require.NoError(t, os.WriteFile(filepath.Join(pageDir, "account.tmpl"), []byte(pageContent), 0644))
+
+		engine, err := NewEngine(testDir, DefaultLoadTemplate, nil)
+		require.NoError(t, err)
+		t.Cleanup(func() { engine.Close() })
+		require.NoError(t, engine.Init())
+		return engine
+	}
+
+	t.Run("OrderedHooksAndFilters", func(t *testing.T) {
+		engine := newEngine(t)
+		var calls []string
+		engine.Use(
+			BeforeRender(func(info *RenderInfo) error {
+				calls = append(calls, "before1")
+				assert.Equal(t, KindPage, info.Kind)
+				assert.Equal(t, "account", info.Name)
+				assert.Equal(t, "layout.tmpl:pages/account", info.Template)
+				info.Data["user"] = "alice"
+				return nil
+			}),
+			AfterRender(func(info *RenderInfo, output []byte) ([]byte, error) {
+				calls = append(calls, "after1")
+				return bytes.Replace(output, []byte("</body>"), []byte("<script>track()</script></body>"), 1), nil
+			}),
+		)
+		engine.Use(
+			BeforeRender(func(info *RenderInfo) error {
+				calls = append(calls, "before2")
+				return nil
+			}),
+			AfterRender(func(info *RenderInfo, output []byte) ([]byte, error) {
+				calls = append(calls, "after2")
+				return bytes.ReplaceAll(output, []byte("/static/"), []byte("https://cdn.example.com/")), nil
+			}),
+		)
+
+		data := H{"title": "账户"}
+		var buf bytes.Buffer
+		require.NoError(t, engine.RenderPage(&buf, "account", data))
+
+		assert.Equal(t, []string{"before1", "before2", "after1", "after2"}, calls)
+		assert.Contains(t, buf.String(), "<p>alice</p>")
+		assert.Contains(t, buf.String(), "<script>track()</script></body>")
+		// 调用方的数据不受钩子影响
+		assert.NotContains(t, data, "user")
+	})
+
+	t.Run("BeforeHookError", func(t *testing.T) {
+		engine := newEngine(t)
+		hookErr := errors.New("not logged in")
+		engine.Use(BeforeRender(func(info *RenderInfo) error {
+			return hookErr
+		}))
+
+		var buf bytes.Buffer
+		assert.ErrorIs(t, engine.RenderPage(&buf, "account", nil), hookErr)
+		assert.Zero(t, buf.Len())
+	})
+
+	t.Run("FilterErrorWritesNothing", func(t *testing.T) {
+		engine := newEngine(t)
+		filterErr := errors.New("filter failed")
+		engine.Use(AfterRender(func(info *RenderInfo, output []byte) ([]byte, error) {
+			return nil, filterErr
+		}))
+
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		rec := httptest.NewRecorder()
+		assert.ErrorIs(t, engine.HTMLPage(rec, req, "account", nil), filterErr)
+		assert.Zero(t, rec.Body.Len())
+	})
+
+	t.Run("RequestInHTTPMethods", func(t *testing.T) {
+		engine := newEngine(t)
+		engine.Use(BeforeRender(func(info *RenderInfo) error {
+			require.NotNil(t, info.Request)
+			info.Data["user"] = info.Request.Header.Get("X-User")
+			return nil
+		}))
+
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("X-User", "bob")
+		rec := httptest.NewRecorder()
+		require.NoError(t, engine.HTMLPage(rec, req, "account", nil))
+		assert.Contains(t, rec.Body.String(), "<p>bob</p>")
+	})
+}
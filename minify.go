@@ -0,0 +1,306 @@
+package template
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+)
+
+// blockTags 前后的空白不影响显示的标签，其两侧的空白会被删除
+var blockTags = map[string]bool{
+	"!doctype": true, "html": true, "head": true, "body": true, "title": true, "meta": true, "link": true,
+	"script": true, "style": true, "noscript": true, "base": true,
+	"div": true, "p": true, "ul": true, "ol": true, "li": true, "dl": true, "dt": true, "dd": true,
+	"table": true, "thead": true, "tbody": true, "tfoot": true, "tr": true, "td": true, "th": true, "caption": true,
+	"section": true, "article": true, "aside": true, "header": true, "footer": true, "nav": true, "main": true,
+	"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+	"form": true, "fieldset": true, "legend": true, "hr": true, "br": true, "blockquote": true,
+	"figure": true, "figcaption": true, "pre": true, "option": true,
+}
+
+// scriptTypePattern 提取 script 标签的 type 属性
+var scriptTypePattern = regexp.MustCompile(`(?i)\stype\s*=\s*["']?([^"'\s>]+)`)
+
+// MinifyHTML 压缩 HTML：合并空白、删除注释（保留 IE 条件注释）、压缩内联 CSS 和 JavaScript，
+// <pre> 和 <textarea> 的内容以及属性值保持不变
+func MinifyHTML(src []byte) []byte {
+	m := &minifier{src: src, out: make([]byte, 0, len(src))}
+	m.run()
+	return m.out
+}
+
+// minifier HTML 压缩状态
+type minifier struct {
+	src       []byte
+	out       []byte
+	space     bool // 是否有待输出的空白
+	lastBlock bool // 上一个输出的是否为块级标签
+}
+
+// run 逐个处理文本、注释和标签
+func (m *minifier) run() {
+	src := m.src
+	for i := 0; i < len(src); {
+		c := src[i]
+		if c == '<' {
+			if bytes.HasPrefix(src[i:], []byte("<!--")) {
+				i = m.comment(i)
+				continue
+			}
+			if name, end, closing, ok := parseTag(src, i); ok {
+				m.flushSpace(blockTags[name])
+				m.tag(src[i:end])
+				m.lastBlock = blockTags[name]
+				if closing {
+					i = end
+				} else {
+					i = m.rawText(name, src[i:end], end)
+				}
+				continue
+			}
+		}
+		if isHTMLSpace(c) {
+			m.space = true
+			i++
+			continue
+		}
+		m.flushSpace(false)
+		m.out = append(m.out, c)
+		m.lastBlock = false
+		i++
+	}
+}
+
+// flushSpace 输出待输出的空白，块级标签两侧和文档开头的空白直接丢弃
+func (m *minifier) flushSpace(nextBlock bool) {
+	if !m.space {
+		return
+	}
+	m.space = false
+	if len(m.out) > 0 && !m.lastBlock && !nextBlock {
+		m.out = append(m.out, ' ')
+	}
+}
+
+// comment 处理注释，返回注释之后的位置
+func (m *minifier) comment(i int) int {
+	src := m.src
+	end := bytes.Index(src[i+4:], []byte("-->"))
+	if end < 0 {
+		m.flushSpace(false)
+		m.out = append(m.out, src[i:]...)
+		return len(src)
+	}
+	end += i + 4 + 3
+	// IE 条件注释需要保留
+	if bytes.HasPrefix(src[i:], []byte("<!--[if")) || bytes.HasPrefix(src[i:], []byte("<!--<![endif]")) {
+		m.flushSpace(false)
+		m.out = append(m.out, src[i:end]...)
+		m.lastBlock = false
+	}
+	return end
+}
+
+// tag 输出标签，合并引号之外的空白
+func (m *minifier) tag(tag []byte) {
+	var quote byte
+	unquoted := false // 是否在不带引号的属性值中
+	for i := 0; i < len(tag); i++ {
+		c := tag[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+			m.out = append(m.out, c)
+		case c == '"' || c == '\'':
+			quote = c
+			m.out = append(m.out, c)
+		case isHTMLSpace(c):
+			j := i
+			for j < len(tag) && isHTMLSpace(tag[j]) {
+				j++
+			}
+			next := tag[j]
+			prev := m.out[len(m.out)-1]
+			// 删除 > 之前以及 = 两侧的空白，不带引号的属性值之后的 /> 会被当作属性值的一部分，需要保留空白
+			selfClosing := next == '/' && j+1 < len(tag) && tag[j+1] == '>'
+			if next != '>' && next != '=' && prev != '=' && (!selfClosing || unquoted) {
+				m.out = append(m.out, ' ')
+			}
+			unquoted = false
+			i = j - 1
+		default:
+			unquoted = c != '=' && (unquoted || i > 0 && m.out[len(m.out)-1] == '=')
+			m.out = append(m.out, c)
+		}
+	}
+}
+
+// rawText 处理 script、style、pre 和 textarea 的内容，返回内容之后（结束标签处）的位置
+func (m *minifier) rawText(name string, openTag []byte, i int) int {
+	if name != "script" && name != "style" && name != "pre" && name != "textarea" {
+		return i
+	}
+	end := indexFold(m.src[i:], "</"+name)
+	if end < 0 {
+		end = len(m.src)
+	} else {
+		end += i
+	}
+	content := m.src[i:end]
+
+	switch name {
+	case "script":
+		if isJavaScript(openTag) {
+			content = minifyJS(content)
+		}
+	case "style":
+		content = minifyCSS(content)
+	}
+	m.out = append(m.out, content...)
+	m.lastBlock = false
+	return end
+}
+
+// parseTag 解析 i 处的标签，返回小写的标签名、标签结束后的位置和是否为结束标签
+func parseTag(src []byte, i int) (name string, end int, closing bool, ok bool) {
+	j := i + 1
+	if j < len(src) && src[j] == '/' {
+		closing = true
+		j++
+	}
+	start := j
+	if j < len(src) && src[j] == '!' && !closing {
+		j++
+	}
+	if j >= len(src) || !isLetter(src[j]) {
+		return "", 0, false, false
+	}
+	for j < len(src) && (isLetter(src[j]) || isDigit(src[j]) || src[j] == '-' || src[j] == ':') {
+		j++
+	}
+	name = strings.ToLower(string(src[start:j]))
+
+	var quote byte
+	for ; j < len(src); j++ {
+		c := src[j]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '>':
+			return name, j + 1, closing, true
+		}
+	}
+	return "", 0, false, false
+}
+
+// isJavaScript 判断 script 标签的内容是否为 JavaScript
+func isJavaScript(openTag []byte) bool {
+	match := scriptTypePattern.FindSubmatch(openTag)
+	if match == nil {
+		return true
+	}
+	typ := strings.ToLower(string(match[1]))
+	return typ == "module" || strings.Contains(typ, "javascript") || strings.Contains(typ, "ecmascript")
+}
+
+// minifyJS 保守地压缩 JavaScript：删除每行的缩进、空行和整行注释，保留换行以免影响自动插入分号。
+// 包含模板字符串时行首空白可能属于字符串内容，只去掉首尾空白。
+func minifyJS(src []byte) []byte {
+	if bytes.IndexByte(src, '`') >= 0 {
+		return bytes.TrimSpace(src)
+	}
+	var out []byte
+	for _, line := range bytes.Split(src, []byte("\n")) {
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 || bytes.HasPrefix(line, []byte("//")) {
+			continue
+		}
+		if len(out) > 0 {
+			out = append(out, '\n')
+		}
+		out = append(out, line...)
+	}
+	return out
+}
+
+// minifyCSS 压缩 CSS：删除注释，合并空白，删除 { } ; , > 两侧的空白和最后一个声明的分号
+func minifyCSS(src []byte) []byte {
+	const tight = "{};,>"
+	out := make([]byte, 0, len(src))
+	space := false
+	for i := 0; i < len(src); i++ {
+		c := src[i]
+		switch {
+		case c == '"' || c == '\'':
+			end := i + 1
+			for end < len(src) && src[end] != c {
+				if src[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(src) {
+				end = len(src) - 1
+			}
+			if space && len(out) > 0 && !strings.ContainsRune(tight, rune(out[len(out)-1])) {
+				out = append(out, ' ')
+			}
+			space = false
+			out = append(out, src[i:end+1]...)
+			i = end
+		case c == '/' && i+1 < len(src) && src[i+1] == '*':
+			end := bytes.Index(src[i+2:], []byte("*/"))
+			if end < 0 {
+				return out
+			}
+			i += end + 3
+		case isHTMLSpace(c):
+			space = true
+		default:
+			if strings.IndexByte(tight, c) >= 0 {
+				space = false
+				if c == '}' && len(out) > 0 && out[len(out)-1] == ';' {
+					out = out[:len(out)-1]
+				}
+			} else if space && len(out) > 0 && !strings.ContainsRune(tight, rune(out[len(out)-1])) {
+				out = append(out, ' ')
+			}
+			space = false
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// indexFold 不区分大小写地查找以 < 开头的子串
+func indexFold(src []byte, sub string) int {
+	for i := 0; i+len(sub) <= len(src); i++ {
+		j := bytes.IndexByte(src[i:], '<')
+		if j < 0 {
+			return -1
+		}
+		i += j
+		if i+len(sub) <= len(src) && strings.EqualFold(string(src[i:i+len(sub)]), sub) {
+			return i
+		}
+	}
+	return -1
+}
+
+func isHTMLSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f'
+}
+
+func isLetter(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
@@ -0,0 +1,89 @@
+package template
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestMinifyHTML 测试 HTML 压缩
+func TestMinifyHTML(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{
+			name: "BlockWhitespace",
+			src:  "<!DOCTYPE html>\n<html>\n  <head>\n    <title> 标题 </title>\n  </head>\n  <body>\n    <div>\n      <p>a <b>b</b>  c</p>\n    </div>\n  </body>\n</html>\n",
+			want: "<!DOCTYPE html><html><head><title>标题</title></head><body><div><p>a <b>b</b> c</p></div></body></html>",
+		},
+		{
+			name: "Comments",
+			src:  "<p>a <!-- 注释 --> b</p><!--[if IE]><p>ie</p><![endif]-->",
+			want: "<p>a b</p><!--[if IE]><p>ie</p><![endif]-->",
+		},
+		{
+			name: "Attributes",
+			src:  "<a   href = \"/a  b\"\n   class='x   y' >link</a><br />",
+			want: "<a href=\"/a  b\" class='x   y'>link</a><br/>",
+		},
+		{
+			name: "UnquotedSelfClosing",
+			src:  "<img src=a\n/><img src = b  alt=\"c\"  /><input value=x=y />",
+			want: "<img src=a /><img src=b alt=\"c\"/><input value=x=y />",
+		},
+		{
+			name: "PreAndTextarea",
+			src:  "<div>\n  <pre>\n  code  block\n</pre>\n  <textarea>  keep\n  this </textarea>\n</div>",
+			want: "<div><pre>\n  code  block\n</pre><textarea>  keep\n  this </textarea></div>",
+		},
+		{
+			name: "InlineCSS",
+			src:  "<style>\n  /* 注释 */\n  a > b ,\n  .c:hover {\n    color : red;\n    font-family: \"A  B\", serif;\n  }\n  @media screen and (max-width: 600px) { p { margin: 0 } }\n</style>",
+			want: "<style>a>b,.c:hover{color : red;font-family: \"A  B\",serif}@media screen and (max-width: 600px){p{margin: 0}}</style>",
+		},
+		{
+			name: "InlineJS",
+			src:  "<script>\n  // 注释\n  var a = 1\n\n  if (a) {\n    go(\"x  y\")\n  }\n</script>",
+			want: "<script>var a = 1\nif (a) {\ngo(\"x  y\")\n}</script>",
+		},
+		{
+			name: "TemplateLiteral",
+			src:  "<script>\n  const html = `\n    <p>  x </p>\n  `\n</script>",
+			want: "<script>const html = `\n    <p>  x </p>\n  `</script>",
+		},
+		{
+			name: "NonJavaScriptScript",
+			src:  "<script type=\"text/x-template\">\n  <div>  {{ x }} </div>\n</script>",
+			want: "<script type=\"text/x-template\">\n  <div>  {{ x }} </div>\n</script>",
+		},
+		{
+			name: "UppercaseTags",
+			src:  "<PRE>  a  </PRE>\n<P> b </P>",
+			want: "<PRE>  a  </PRE><P>b</P>",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, string(MinifyHTML([]byte(tt.src))))
+		})
+	}
+}
+
+// TestMinifyOption 测试渲染时启用压缩
+func TestMinifyOption(t *testing.T) {
+	engine, err := NewEngine(createRenderTestStructure(t, true), DefaultLoadTemplate, nil)
+	require.NoError(t, err)
+	defer engine.Close()
+	require.NoError(t, engine.Init())
+
+	var plain, minified bytes.Buffer
+	require.NoError(t, engine.RenderPage(&plain, "sample", H{"title": "压缩", "content": "内容"}))
+	require.NoError(t, engine.RenderPage(&minified, "sample", H{"title": "压缩", "content": "内容"}, Minify(true)))
+
+	assert.Less(t, minified.Len(), plain.Len())
+	assert.Equal(t, "<!DOCTYPE html><html><head><title>压缩</title></head><body><h1>压缩</h1><p>内容</p></body></html>", minified.String())
+}
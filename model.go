@@ -43,31 +43,97 @@ func (r Render) Add(name string, tmpl *template.Template) error {
 	return nil
 }
 
-// AddFromFilesFuncs supply add template from file callback func
+// AddFromFilesFuncs supply add template from file callback func, panics on parse failure
 func (r Render) AddFromFilesFuncs(name string, funcMap FuncMap, files ...string) *template.Template {
-	tname := filepath.Base(files[0])
-	tmpl := template.Must(template.New(tname).Funcs(template.FuncMap(funcMap)).ParseFiles(files...))
+	tmpl, err := parseFiles(funcMap, files, func(t *template.Template, file string) (*template.Template, error) {
+		return t.ParseFiles(file)
+	})
+	if err != nil {
+		panic(err)
+	}
 	r.Add(name, tmpl)
 	return tmpl
 }
 
-// AddFromFSFuncs supply add template from fs callback func
+// AddFromFilesFuncsE 从文件添加模板，解析失败时返回 *ParseError，多个文件出错时返回 LoadErrors
+func (r Render) AddFromFilesFuncsE(name string, funcMap FuncMap, files ...string) (*template.Template, error) {
+	tmpl, err := parseFiles(funcMap, files, func(t *template.Template, file string) (*template.Template, error) {
+		return t.ParseFiles(file)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return tmpl, r.Add(name, tmpl)
+}
+
+// AddFromFSFuncs supply add template from fs callback func, panics on parse failure
 func (r Render) AddFromFSFuncs(name string, funcMap FuncMap, fs fs.FS, files ...string) *template.Template {
-	tname := filepath.Base(files[0])
-	tmpl := template.Must(template.New(tname).Funcs(template.FuncMap(funcMap)).ParseFS(fs, files...))
+	tmpl, err := parseFiles(funcMap, files, func(t *template.Template, file string) (*template.Template, error) {
+		return t.ParseFS(fs, file)
+	})
+	if err != nil {
+		panic(err)
+	}
 	r.Add(name, tmpl)
 	return tmpl
 }
 
-// Execute 执行
+// AddFromFSFuncsE 从文件系统添加模板，解析失败时返回 *ParseError，多个文件出错时返回 LoadErrors
+func (r Render) AddFromFSFuncsE(name string, funcMap FuncMap, fs fs.FS, files ...string) (*template.Template, error) {
+	tmpl, err := parseFiles(funcMap, files, func(t *template.Template, file string) (*template.Template, error) {
+		return t.ParseFS(fs, file)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return tmpl, r.Add(name, tmpl)
+}
+
+// parseFiles 以第一个文件的基本名称创建模板并逐个解析模板文件，见 parseInto
+func parseFiles(funcMap FuncMap, files []string, parse func(t *template.Template, file string) (*template.Template, error)) (*template.Template, error) {
+	return parseInto(template.New(filepath.Base(files[0])).Funcs(template.FuncMap(funcMap)), files, parse)
+}
+
+// parseInto 逐个解析模板文件到 tmpl 中，出错的文件记录为定位到该文件的 *ParseError 后继续解析其余文件
+func parseInto(tmpl *template.Template, files []string, parse func(t *template.Template, file string) (*template.Template, error)) (*template.Template, error) {
+	var errs LoadErrors
+	for _, file := range files {
+		if _, err := parse(tmpl, file); err != nil {
+			errs.add(newParseError(err, file))
+			continue
+		}
+		recordFileName(tmpl, filepath.Base(file), file)
+	}
+	if len(errs) == 1 {
+		return nil, errs[0]
+	}
+	if len(errs) > 1 {
+		return nil, errs
+	}
+	return tmpl, nil
+}
+
+// Execute 执行，模板不存在时返回 ErrTemplateNotFound
 func (r Render) Execute(name string, wr io.Writer, data interface{}) error {
 	t, ok := r[name]
 	if !ok {
-		return fmt.Errorf("template %s not exists", name)
+		return &templateNotFoundError{name: name}
 	}
 	return t.Execute(wr, data)
 }
 
+// ExecuteBlock 只执行模板集合中的指定块（{{define}} 定义的模板），不包含外层布局
+func (r Render) ExecuteBlock(name, block string, wr io.Writer, data interface{}) error {
+	t, ok := r[name]
+	if !ok {
+		return &templateNotFoundError{name: name}
+	}
+	if t.Lookup(block) == nil {
+		return fmt.Errorf("block %s not defined in template %s", block, name)
+	}
+	return t.ExecuteTemplate(wr, block, data)
+}
+
 // HasTemplate 检查是否存在指定名称的模板
 func (r Render) HasTemplate(name string) bool {
 	_, ok := r[name]
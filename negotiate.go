@@ -0,0 +1,219 @@
+package template
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// ErrInvalidXMLName 返回 XML 时数据中的键不能作为元素名称
+var ErrInvalidXMLName = errors.New("template: data key is not a valid XML name")
+
+// 内容协商支持的格式
+const (
+	formatHTML = "html"
+	formatJSON = "json"
+	formatXML  = "xml"
+)
+
+// negotiableTypes 按服务端偏好排序的媒体类型，客户端权重相同时靠前的优先
+var negotiableTypes = []struct {
+	mediaType string
+	format    string
+}{
+	{"text/html", formatHTML},
+	{"application/xhtml+xml", formatHTML},
+	{"application/json", formatJSON},
+	{"application/xml", formatXML},
+	{"text/xml", formatXML},
+}
+
+// negotiateFormat 根据 Accept 请求头选择响应格式，无法匹配时返回 HTML
+func negotiateFormat(accept string) string {
+	if strings.TrimSpace(accept) == "" {
+		return formatHTML
+	}
+
+	// 权重相同时优先明确列出的类型，如 "application/json, */*" 选择 JSON
+	best, bestQ, bestSpecificity := formatHTML, 0.0, -1
+	for _, candidate := range negotiableTypes {
+		q, specificity := acceptQuality(accept, candidate.mediaType)
+		if q > bestQ || q == bestQ && q > 0 && specificity > bestSpecificity {
+			best, bestQ, bestSpecificity = candidate.format, q, specificity
+		}
+	}
+	return best
+}
+
+// acceptQuality 返回 Accept 请求头中与媒体类型最匹配的范围的权重和匹配程度，未匹配时权重为 0
+func acceptQuality(accept, mediaType string) (quality float64, specificity int) {
+	typ, _, _ := strings.Cut(mediaType, "/")
+	specificity = -1
+	for _, part := range strings.Split(accept, ",") {
+		params := strings.Split(part, ";")
+		mediaRange := strings.ToLower(strings.TrimSpace(params[0]))
+
+		var s int
+		switch {
+		case mediaRange == mediaType:
+			s = 2
+		case mediaRange == typ+"/*":
+			s = 1
+		case mediaRange == "*/*":
+			s = 0
+		default:
+			continue
+		}
+		if s < specificity {
+			continue
+		}
+
+		q := 1.0
+		for _, param := range params[1:] {
+			key, value, ok := strings.Cut(strings.TrimSpace(param), "=")
+			if ok && strings.TrimSpace(key) == "q" {
+				if parsed, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err == nil {
+					q = parsed
+				}
+			}
+		}
+		quality, specificity = q, s
+	}
+	return quality, specificity
+}
+
+// responseFormat 本次响应的格式，未启用内容协商时总是 HTML
+func (job *renderJob) responseFormat() string {
+	if !job.opt.Negotiate || job.request == nil {
+		return formatHTML
+	}
+	return negotiateFormat(job.request.Header.Get("Accept"))
+}
+
+// writeData 将页面数据序列化为 JSON 或 XML 写入HTTP响应，不执行模板
+func (en *Engine) writeData(w http.ResponseWriter, job *renderJob, format string) error {
+	if err := job.beforeRender(); err != nil {
+		return err
+	}
+
+	payload := make(H, len(job.data))
+	for key, value := range job.data {
+		payload[key] = value
+	}
+	// 渲染上下文不可序列化，全局常量和变量只在请求时输出
+	if job.opt.ContextKey != "" {
+		delete(payload, job.opt.ContextKey)
+	}
+	if !job.opt.NegotiateGlobals {
+		delete(payload, job.opt.ConstantKey)
+		delete(payload, job.opt.VariableKey)
+	}
+
+	buf := getBuffer()
+	defer putBuffer(buf)
+
+	var contentType string
+	switch format {
+	case formatJSON:
+		contentType = "application/json; charset=utf-8"
+		if err := json.NewEncoder(buf).Encode(payload); err != nil {
+			return err
+		}
+	case formatXML:
+		contentType = "application/xml; charset=utf-8"
+		buf.WriteString(xml.Header)
+		enc := xml.NewEncoder(buf)
+		if err := encodeXML(enc, "data", payload); err != nil {
+			return err
+		}
+		if err := enc.Flush(); err != nil {
+			return err
+		}
+	}
+
+	header := w.Header()
+	header.Set("Content-Type", contentType)
+	statusCode := job.opt.StatusCode
+	if statusCode == 0 {
+		statusCode = http.StatusOK
+	}
+	w.WriteHeader(statusCode)
+	_, err := buf.WriteTo(w)
+	return err
+}
+
+// encodeXML 将值编码为名为 name 的元素：键为字符串的 map 按键排序编码为子元素，
+// 键不是合法的元素名称时返回 ErrInvalidXMLName，切片的每一项编码为 item 子元素，
+// 其他值使用 encoding/xml 的规则
+func encodeXML(enc *xml.Encoder, name string, value any) error {
+	start := xml.StartElement{Name: xml.Name{Local: name}}
+	if value == nil {
+		return enc.EncodeElement("", start)
+	}
+
+	v := reflect.ValueOf(value)
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return enc.EncodeElement("", start)
+		}
+		v = v.Elem()
+	}
+
+	switch {
+	case v.Kind() == reflect.Map && v.Type().Key().Kind() == reflect.String:
+		keys := make([]string, 0, v.Len())
+		for _, key := range v.MapKeys() {
+			if !isXMLName(key.String()) {
+				return fmt.Errorf("%w: %q", ErrInvalidXMLName, key.String())
+			}
+			keys = append(keys, key.String())
+		}
+		sort.Strings(keys)
+
+		if err := enc.EncodeToken(start); err != nil {
+			return err
+		}
+		for _, key := range keys {
+			if err := encodeXML(enc, key, v.MapIndex(reflect.ValueOf(key).Convert(v.Type().Key())).Interface()); err != nil {
+				return err
+			}
+		}
+		return enc.EncodeToken(start.End())
+	case (v.Kind() == reflect.Slice || v.Kind() == reflect.Array) && v.Type().Elem().Kind() != reflect.Uint8:
+		if err := enc.EncodeToken(start); err != nil {
+			return err
+		}
+		for i := 0; i < v.Len(); i++ {
+			if err := encodeXML(enc, "item", v.Index(i).Interface()); err != nil {
+				return err
+			}
+		}
+		return enc.EncodeToken(start.End())
+	default:
+		return enc.EncodeElement(v.Interface(), start)
+	}
+}
+
+// isXMLName 检查 name 是否可以作为不带命名空间前缀的 XML 元素名称：
+// 以字母或下划线开头，只包含字母、数字、下划线、连字符和点，且不以 xml 开头
+func isXMLName(name string) bool {
+	if name == "" || strings.HasPrefix(strings.ToLower(name), "xml") {
+		return false
+	}
+	for i, r := range name {
+		switch {
+		case unicode.IsLetter(r) || r == '_':
+		case i > 0 && (unicode.IsDigit(r) || r == '-' || r == '.'):
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,111 @@
+package template
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestNegotiateFormat 测试根据 Accept 请求头选择响应格式
+func TestNegotiateFormat(t *testing.T) {
+	tests := []struct {
+		accept string
+		want   string
+	}{
+		{"", formatHTML},
+		{"*/*", formatHTML},
+		{"text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8", formatHTML},
+		{"application/json", formatJSON},
+		{"application/json, text/plain, */*", formatJSON},
+		{"text/html;q=0.5, application/json", formatJSON},
+		{"application/xml", formatXML},
+		{"text/*;q=0.3, application/xml;q=0.2", formatHTML},
+		{"image/png", formatHTML},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, negotiateFormat(tt.accept), tt.accept)
+	}
+}
+
+// TestHTMLNegotiation 测试 HTTP 方法的内容协商
+func TestHTMLNegotiation(t *testing.T) {
+	engine, err := NewEngine(createRenderTestStructure(t, true), DefaultLoadTemplate, nil,
+		GlobalConstant(map[string]any{"site": "blog"}),
+		Negotiate(true),
+	)
+	require.NoError(t, err)
+	defer engine.Close()
+	require.NoError(t, engine.Init())
+
+	request := func(accept string, opts ...Option) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/posts", nil)
+		req.Header.Set("Accept", accept)
+		rec := httptest.NewRecorder()
+		data := H{"title": "文章", "posts": []H{{"id": 1}, {"id": 2}}}
+		require.NoError(t, engine.HTMLPage(rec, req, "sample", data, opts...))
+		return rec
+	}
+
+	t.Run("Browser", func(t *testing.T) {
+		rec := request("text/html,*/*;q=0.8")
+		assert.Equal(t, "text/html; charset=utf-8", rec.Header().Get("Content-Type"))
+		assert.Equal(t, "Accept", rec.Header().Get("Vary"))
+		assert.Contains(t, rec.Body.String(), "<h1>文章</h1>")
+	})
+
+	t.Run("JSON", func(t *testing.T) {
+		rec := request("application/json", StatusCode(http.StatusCreated))
+		assert.Equal(t, http.StatusCreated, rec.Code)
+		assert.Equal(t, "application/json; charset=utf-8", rec.Header().Get("Content-Type"))
+
+		var payload map[string]any
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &payload))
+		assert.Equal(t, "文章", payload["title"])
+		assert.Len(t, payload["posts"], 2)
+		assert.NotContains(t, payload, "constant")
+		assert.NotContains(t, payload, "variable")
+		assert.NotContains(t, payload, "context")
+	})
+
+	t.Run("JSONWithGlobals", func(t *testing.T) {
+		rec := request("application/json", NegotiateGlobals(true))
+
+		var payload map[string]any
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &payload))
+		assert.Equal(t, map[string]any{"site": "blog"}, payload["constant"])
+		assert.NotContains(t, payload, "context")
+	})
+
+	t.Run("XML", func(t *testing.T) {
+		rec := request("application/xml")
+		assert.Equal(t, "application/xml; charset=utf-8", rec.Header().Get("Content-Type"))
+		assert.Equal(t, `<?xml version="1.0" encoding="UTF-8"?>`+"\n"+
+			`<data><posts><item><id>1</id></item><item><id>2</id></item></posts><title>文章</title></data>`, rec.Body.String())
+	})
+
+	t.Run("XMLInvalidName", func(t *testing.T) {
+		for _, key := range []string{`a><script>alert(1)</script><b`, "1st", "a b", "xmlns", "ns:key", ""} {
+			var buf bytes.Buffer
+			err := encodeXML(xml.NewEncoder(&buf), "data", H{key: "v"})
+			assert.ErrorIs(t, err, ErrInvalidXMLName, key)
+			assert.NotContains(t, buf.String(), "<script>")
+		}
+
+		var buf bytes.Buffer
+		enc := xml.NewEncoder(&buf)
+		require.NoError(t, encodeXML(enc, "data", H{"post-1.title": "a", "_名称": "b"}))
+		require.NoError(t, enc.Flush())
+		assert.Equal(t, `<data><_名称>b</_名称><post-1.title>a</post-1.title></data>`, buf.String())
+	})
+
+	t.Run("Disabled", func(t *testing.T) {
+		rec := request("application/json", Negotiate(false))
+		assert.Contains(t, rec.Body.String(), "<h1>文章</h1>")
+	})
+}
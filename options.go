@@ -1,6 +1,9 @@
 package template
 
-import "net/http"
+import (
+	"net/http"
+	"time"
+)
 
 // Options 可选参数列表
 type Options struct {
@@ -9,6 +12,30 @@ type Options struct {
 	GlobalVariable map[string]any
 	GlobalConstant map[string]any
 	Suffix         string
+	Extensions     []string // 模板文件扩展名，见 Extensions
+	// 模板数据保留键名，为空时不写入
+	ConstantKey string // 全局常量的键名
+	VariableKey string // 全局变量的键名
+	ContextKey  string // 渲染上下文的键名，默认为空，见 ContextKey
+	// 缓冲渲染相关字段
+	Buffered      bool   // 是否先渲染到缓冲区，成功后再写入输出
+	FallbackError string // 缓冲渲染失败时改为渲染的错误页面名称，为空时不回退
+	Minify        bool   // 是否压缩输出的 HTML，见 MinifyHTML
+	Debug         bool   // 是否在输出末尾附加渲染调试信息，见 Debug
+	// 输出缓存相关字段
+	CacheSize int           // 输出缓存最多保存的条目数，为 0 时不缓存
+	CacheKey  string        // 本次渲染的缓存键，为空时不使用缓存
+	CacheTTL  time.Duration // 本次渲染结果的缓存时间，为 0 时不过期
+	CacheTags []string      // 本次渲染结果的缓存标签，用于 InvalidateCache
+	// 执行保护相关字段
+	RecoverPanics    bool          // 是否将模板执行中的 panic 转换为 *PanicError
+	MaxOutputSize    int64         // 单次渲染最多输出的字节数，为 0 时不限制
+	MaxExecutionTime time.Duration // 单次渲染的最长执行时间，为 0 时不限制
+	// 内容协商相关字段
+	Negotiate        bool // HTTP 方法是否根据 Accept 请求头返回 JSON 或 XML 格式的页面数据
+	NegotiateGlobals bool // 返回的页面数据中是否包含全局常量和变量
+	// 片段渲染相关字段
+	FragmentBlock string // HTMX 请求只渲染的块名称
 	// 主题相关字段
 	Theme          string // 指定的主题名称
 	DefaultTheme   string // 默认主题名称
@@ -23,6 +50,26 @@ func newOptions(opts ...Option) Options {
 		GlobalVariable: map[string]any{},
 		GlobalConstant: map[string]any{},
 		Suffix:         "tmpl",
+		// 模板数据保留键名默认值
+		ConstantKey: "constant",
+		VariableKey: "variable",
+		ContextKey:  "", // 默认不写入，避免与调用方数据中已有的 context 键冲突
+		// 缓冲渲染默认值
+		Buffered:      false,
+		FallbackError: "500",
+		Minify:        false,
+		Debug:         false,
+		// 输出缓存默认值
+		CacheSize: 1000,
+		// 执行保护默认值
+		RecoverPanics:    false,
+		MaxOutputSize:    0,
+		MaxExecutionTime: 0,
+		// 内容协商默认值
+		Negotiate:        false,
+		NegotiateGlobals: false,
+		// 片段渲染默认值
+		FragmentBlock: "content",
 		// 主题相关默认值
 		Theme:          "",    // 空字符串表示未指定主题
 		DefaultTheme:   "",    // 空字符串表示使用自动检测的默认主题
@@ -65,12 +112,132 @@ func GlobalConstant(constant map[string]any) Option {
 	}
 }
 
+// ReservedKeys 设置模板数据中全局常量、全局变量和渲染上下文的键名，
+// 默认为 "constant"、"variable" 和空字符串，为空字符串时不写入对应的数据
+func ReservedKeys(constant, variable, context string) Option {
+	return func(o *Options) {
+		o.ConstantKey = constant
+		o.VariableKey = variable
+		o.ContextKey = context
+	}
+}
+
+// ContextKey 以 key 为键将渲染上下文写入模板数据，默认不写入。
+// 模板数据只在顶层作用域中是 .，在 {{with}}、{{range}} 中需要使用 $.context，
+// 调用 {{template "x" .post}} 时被调用的模板无法访问上下文，需要传入包含上下文的数据
+func ContextKey(key string) Option {
+	return func(o *Options) {
+		o.ContextKey = key
+	}
+}
+
 func Suffix(suffix string) Option {
 	return func(o *Options) {
 		o.Suffix = suffix
 	}
 }
 
+// Extensions 设置模板文件的扩展名，如 Extensions(".html", ".tmpl")，前导点可以省略，
+// 默认加载和解析名称时只使用 .tmpl，监听文件变化和主题校验识别 .tmpl、.html、.gohtml 和 .tpl。
+// 设置后加载模板、监听文件变化、主题校验和模板名称解析只识别这些扩展名，
+// 名称解析时首先使用 Suffix 指定的扩展名，然后按这里的顺序尝试。只应在引擎选项中设置，
+// 内置的 DefaultLoadTemplate 等加载函数自动使用这些扩展名，自定义加载函数见 LoadTemplateWithExtensions
+func Extensions(exts ...string) Option {
+	return func(o *Options) {
+		o.Extensions = normalizeExtensions(exts)
+	}
+}
+
+// Buffered 启用或禁用缓冲渲染，启用后执行出错时不会输出不完整的页面
+func Buffered(enable bool) Option {
+	return func(o *Options) {
+		o.Buffered = enable
+	}
+}
+
+// FallbackError 设置缓冲渲染失败时渲染的错误页面名称，如 "500"，为空时不回退
+func FallbackError(name string) Option {
+	return func(o *Options) {
+		o.FallbackError = name
+	}
+}
+
+// Minify 设置是否压缩输出的 HTML（合并空白、删除注释、压缩内联 CSS 和 JavaScript），启用后先渲染到缓冲区
+func Minify(enable bool) Option {
+	return func(o *Options) {
+		o.Minify = enable
+	}
+}
+
+// Debug 设置是否在输出末尾以 HTML 注释附加渲染调试信息：主题、模板名称、组成模板的文件、
+// 执行的块和渲染耗时。只应在开发环境中使用，启用后先渲染到缓冲区；执行的块只在引擎选项中启用时记录
+func Debug(enable bool) Option {
+	return func(o *Options) {
+		o.Debug = enable
+	}
+}
+
+// CacheSize 设置输出缓存最多保存的条目数，超出时淘汰最久未使用的条目，默认为 1000，为 0 时不缓存
+func CacheSize(entries int) Option {
+	return func(o *Options) {
+		o.CacheSize = entries
+	}
+}
+
+// Cache 缓存本次渲染的输出，缓存键由主题、模板名称和 key 组成。
+// key 需要能区分所有影响输出的数据（包括 BeforeRender 注入的数据）；ttl 为 0 时不过期；
+// tags 用于 InvalidateCache 按标签删除。只应在渲染方法中使用，在引擎选项中设置无效。
+func Cache(key string, ttl time.Duration, tags ...string) Option {
+	return func(o *Options) {
+		o.CacheKey = key
+		o.CacheTTL = ttl
+		o.CacheTags = tags
+	}
+}
+
+// RecoverPanics 设置是否恢复模板执行中的 panic，启用后 panic 作为 *PanicError 返回
+func RecoverPanics(enable bool) Option {
+	return func(o *Options) {
+		o.RecoverPanics = enable
+	}
+}
+
+// MaxOutputSize 设置单次渲染最多输出的字节数，超出时中止执行并返回 ErrOutputLimitExceeded
+func MaxOutputSize(bytes int64) Option {
+	return func(o *Options) {
+		o.MaxOutputSize = bytes
+	}
+}
+
+// MaxExecutionTime 设置单次渲染的最长执行时间，超出后在下一次输出时中止执行并返回 ErrExecutionTimeout
+func MaxExecutionTime(d time.Duration) Option {
+	return func(o *Options) {
+		o.MaxExecutionTime = d
+	}
+}
+
+// Negotiate 设置 HTTP 方法是否根据 Accept 请求头进行内容协商：
+// 客户端更偏好 application/json 或 application/xml 时，不执行模板，直接返回序列化的页面数据
+func Negotiate(enable bool) Option {
+	return func(o *Options) {
+		o.Negotiate = enable
+	}
+}
+
+// NegotiateGlobals 设置内容协商返回的页面数据中是否包含全局常量和变量，默认不包含
+func NegotiateGlobals(include bool) Option {
+	return func(o *Options) {
+		o.NegotiateGlobals = include
+	}
+}
+
+// FragmentBlock 设置 HTMX 请求只渲染的块名称，默认为 "content"
+func FragmentBlock(block string) Option {
+	return func(o *Options) {
+		o.FragmentBlock = block
+	}
+}
+
 // SetTheme 设置指定的主题名称
 func SetTheme(themeName string) Option {
 	return func(o *Options) {
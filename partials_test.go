@@ -0,0 +1,76 @@
+package template
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// partialsFS 返回包含嵌套局部页面和页面局部模板的内存文件系统
+func partialsFS() fstest.MapFS {
+	files := map[string]string{
+		"layouts/layout.tmpl":           `<main>{{ template "content" . }}</main>{{ template "footer" }}`,
+		"partials/footer.tmpl":          `{{ define "footer" }}<footer></footer>{{ end }}`,
+		"partials/forms/input.tmpl":     `<input name="{{ .name }}">`,
+		"partials/tables/input.tmpl":    `<td>{{ .name }}</td>`,
+		"pages/posts/list/list.tmpl":    `{{ define "content" }}{{ template "forms/input" . }}{{ template "tables/input" . }}{{ template "row" }}{{ template "_item" . }}{{ end }}`,
+		"pages/posts/list/_item.tmpl":   `{{ define "row" }}<li>list row</li>{{ end }}<li>{{ .name }}</li>`,
+		"pages/posts/detail/page.tmpl":  `{{ define "content" }}{{ template "row" }}{{ end }}`,
+		"pages/posts/detail/_row.tmpl":  `{{ define "row" }}<p>detail row</p>{{ end }}`,
+		"pages/posts/_shared/_nav.tmpl": `<nav></nav>`,
+		"pages/broken/broken.tmpl":      `{{ define "content" }}{{ template "forms/broken" . }}{{ end }}`,
+		"partials/forms/broken.tmpl":    "<p>\n{{ .user.Name }}</p>",
+	}
+	fsys := fstest.MapFS{}
+	for name, content := range files {
+		fsys[name] = &fstest.MapFile{Data: []byte(content)}
+	}
+	return fsys
+}
+
+// TestNestedPartials 测试子目录中的局部页面以相对路径命名
+func TestNestedPartials(t *testing.T) {
+	engine, err := NewEngineWithFS(partialsFS(), DefaultLoadTemplateFS, nil)
+	require.NoError(t, err)
+	defer engine.Close()
+	require.NoError(t, engine.Init())
+
+	var buf bytes.Buffer
+	require.NoError(t, engine.RenderPage(&buf, "posts/list", H{"name": "title"}))
+	assert.Equal(t, `<main><input name="title"><td>title</td><li>list row</li><li>title</li></main><footer></footer>`, buf.String())
+
+	t.Run("ExecutionErrorFile", func(t *testing.T) {
+		err := engine.RenderPage(&bytes.Buffer{}, "broken", H{"user": "guest"})
+		var tmplErr *TemplateError
+		require.True(t, errors.As(err, &tmplErr))
+		assert.Equal(t, "partials/forms/broken.tmpl", tmplErr.File)
+		assert.Equal(t, 2, tmplErr.Line)
+	})
+
+	t.Run("ParseErrorFile", func(t *testing.T) {
+		fsys := partialsFS()
+		fsys["partials/forms/select.tmpl"] = &fstest.MapFile{Data: []byte("{{ if }}")}
+		_, err := LoadTemplateFS(fsys, ".", nil)
+		var parseErr *ParseError
+		require.True(t, errors.As(err, &parseErr))
+		assert.Equal(t, "partials/forms/select.tmpl", parseErr.File)
+	})
+}
+
+// TestPageLocalPartials 测试页面目录中以下划线开头的局部模板只对该页面可见
+func TestPageLocalPartials(t *testing.T) {
+	render, err := LoadTemplateFS(partialsFS(), ".", nil)
+	require.NoError(t, err)
+
+	// 只有局部模板的目录不是页面
+	assert.False(t, render.HasTemplate("layout.tmpl:pages/posts/_shared"))
+	assert.Nil(t, render["layout.tmpl:pages/posts/detail"].Lookup("_item"))
+
+	var buf bytes.Buffer
+	require.NoError(t, render.Execute("layout.tmpl:pages/posts/detail", &buf, nil))
+	assert.Equal(t, "<main><p>detail row</p></main><footer></footer>", buf.String())
+}
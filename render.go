@@ -0,0 +1,224 @@
+package template
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// 渲染类型
+const (
+	KindPage   = "page"   // 使用布局的页面，见 RenderPage
+	KindSingle = "single" // 单页，见 RenderSingle
+	KindError  = "error"  // 错误页面，见 RenderError
+)
+
+// renderJob 单次渲染所需的全部信息
+type renderJob struct {
+	ctx      context.Context                   // 渲染上下文
+	request  *http.Request                     // HTTP 方法传入的请求
+	render   Render                            // 本次渲染使用的主题渲染器
+	name     string                            // 渲染时传入的名称
+	theme    string                            // 本次渲染使用的主题名称
+	tmplName string                            // 解析后的模板名称
+	typ      string                            // 渲染类型
+	block    string                            // 只渲染的块名称，为空时渲染整个模板
+	data     H                                 // 模板数据
+	opt      Options                           // 合并后的选项
+	hooks    *renderHooks                      // 渲染中间件快照
+	info     *RenderInfo                       // 传给中间件的渲染信息
+	cacheGen uint64                            // 查找缓存时的缓存代数
+	traced   bool                              // 模板加载时插入了记录块执行的语句，见 recordBlocks
+	blocks   *blockRecorder                    // 调试渲染时执行的块
+	cacheHit bool                              // 输出是否来自缓存
+	readFile func(file string) ([]byte, error) // 读取模板文件，用于生成执行错误的源码摘录
+}
+
+// buffered 是否需要先渲染到缓冲区
+func (job *renderJob) buffered() bool {
+	return job.opt.Buffered || job.opt.Minify || job.opt.Debug || job.opt.CacheKey != "" || job.hasFilters()
+}
+
+// lookup 检查要执行的模板和块是否存在，不存在时返回与执行时相同的错误
+func (job *renderJob) lookup() error {
+	t, ok := job.render[job.tmplName]
+	if !ok {
+		return &templateNotFoundError{name: job.tmplName}
+	}
+	if job.block != "" && t.Lookup(job.block) == nil {
+		return fmt.Errorf("block %s not defined in template %s", job.block, job.tmplName)
+	}
+	return nil
+}
+
+// execute 执行模板，指定了块时只执行该块；按选项限制输出大小和执行时间，并恢复执行中的 panic。
+// 模板执行错误转换为 *TemplateError
+func (job *renderJob) execute(w io.Writer) (err error) {
+	if job.opt.RecoverPanics {
+		defer job.recoverPanic(&err)
+	}
+	if job.opt.Debug && job.traced {
+		defer job.startRecording()()
+	}
+	if job.opt.MaxOutputSize > 0 {
+		w = &limitWriter{w: w, remaining: job.opt.MaxOutputSize}
+	}
+	ctx := job.ctx
+	if job.opt.MaxExecutionTime > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeoutCause(ctx, job.opt.MaxExecutionTime, ErrExecutionTimeout)
+		defer cancel()
+	}
+	if ctx.Done() != nil {
+		w = &contextWriter{ctx: ctx, w: w}
+	}
+	if job.block != "" {
+		err = job.render.ExecuteBlock(job.tmplName, job.block, w, job.data)
+	} else {
+		err = job.render.Execute(job.tmplName, w, job.data)
+	}
+	// 模板函数中的 panic 在包装函数中捕获，按本次渲染的选项转换
+	job.recoverFuncPanic(err)
+	return newTemplateError(err, job.theme, job.tmplName, job.readFile)
+}
+
+// maxPooledBufferSize 放回缓冲池的缓冲区容量上限，避免个别大页面长期占用内存
+const maxPooledBufferSize = 1 << 20
+
+// bufferPool 渲染缓冲区池
+var bufferPool = sync.Pool{
+	New: func() any {
+		return new(bytes.Buffer)
+	},
+}
+
+// getBuffer 从缓冲池获取空的缓冲区
+func getBuffer() *bytes.Buffer {
+	buf := bufferPool.Get().(*bytes.Buffer)
+	buf.Reset()
+	return buf
+}
+
+// putBuffer 将缓冲区放回缓冲池
+func putBuffer(buf *bytes.Buffer) {
+	if buf.Cap() > maxPooledBufferSize {
+		return
+	}
+	bufferPool.Put(buf)
+}
+
+// executeBuffered 渲染到缓冲区，成功后再写入 w，失败时改为输出错误页面并返回原始错误
+func (en *Engine) executeBuffered(w io.Writer, job *renderJob) error {
+	buf := getBuffer()
+	defer putBuffer(buf)
+
+	_, err := en.executeWithFallback(buf, job)
+	if buf.Len() > 0 {
+		if _, writeErr := buf.WriteTo(w); writeErr != nil && err == nil {
+			err = writeErr
+		}
+	}
+	return err
+}
+
+// executeWithFallback 渲染到缓冲区，执行出错时清空缓冲区并尝试渲染 FallbackError 错误页面。
+// fallback 表示缓冲区中的内容是否为错误页面；错误页面也渲染失败时缓冲区为空。
+func (en *Engine) executeWithFallback(buf *bytes.Buffer, job *renderJob) (fallback bool, err error) {
+	start := time.Now()
+	err = job.execute(buf)
+	if err == nil {
+		if err = job.filter(buf); err == nil {
+			// 缓存不包含调试信息，命中时重新生成
+			en.storeOutput(job, buf.Bytes())
+			if job.opt.Debug {
+				job.writeDebug(buf, time.Since(start))
+			}
+		}
+		return false, err
+	}
+
+	// 丢弃不完整的输出
+	buf.Reset()
+
+	// 错误页面本身出错时不再回退，避免循环；片段不回退为完整的错误页面；请求已取消时也不再回退
+	if job.typ == KindError || job.block != "" || job.opt.FallbackError == "" || job.ctx.Err() != nil {
+		return false, err
+	}
+
+	errJob, ok := en.fallbackJob(job)
+	if !ok {
+		return false, err
+	}
+	// 错误页面与普通渲染一样执行前置钩子，并受 RecoverPanics 和执行限制的保护
+	if fallbackErr := errJob.beforeRender(); fallbackErr != nil {
+		return false, err
+	}
+	if fallbackErr := errJob.execute(buf); fallbackErr != nil {
+		buf.Reset()
+		return false, err
+	}
+	if filterErr := errJob.filter(buf); filterErr != nil {
+		return false, err
+	}
+	return true, err
+}
+
+// fallbackJob 创建渲染 FallbackError 错误页面的任务，沿用原任务的主题、请求和选项，状态码为 500
+func (en *Engine) fallbackJob(job *renderJob) (*renderJob, bool) {
+	opt := job.opt
+	opt.StatusCode = http.StatusInternalServerError
+	opt.CacheKey, opt.CacheTTL, opt.CacheTags = "", 0, nil
+	data, err := newDataView(job.ctx, H{
+		"status":  http.StatusInternalServerError,
+		"title":   strconv.Itoa(http.StatusInternalServerError),
+		"message": http.StatusText(http.StatusInternalServerError),
+	}, opt)
+	if err != nil {
+		return nil, false
+	}
+	tmplName, ok := en.resolveErrorName(job.render, opt.FallbackError, opt)
+	if !ok {
+		return nil, false
+	}
+	return &renderJob{
+		ctx:      job.ctx,
+		request:  job.request,
+		render:   job.render,
+		name:     opt.FallbackError,
+		theme:    job.theme,
+		tmplName: tmplName,
+		typ:      KindError,
+		data:     data,
+		opt:      opt,
+		hooks:    job.hooks,
+		readFile: job.readFile,
+	}, true
+}
+
+// filter 压缩缓冲区中的输出并执行后置过滤器，出错时清空缓冲区
+func (job *renderJob) filter(buf *bytes.Buffer) error {
+	if !job.opt.Minify && !job.hasFilters() {
+		return nil
+	}
+	output := buf.Bytes()
+	if job.opt.Minify {
+		output = MinifyHTML(output)
+	}
+	if job.hasFilters() {
+		var err error
+		if output, err = job.afterRender(output); err != nil {
+			buf.Reset()
+			return err
+		}
+		// 过滤器可能返回缓冲区自身的切片，先复制再写回
+		output = append([]byte(nil), output...)
+	}
+	buf.Reset()
+	buf.Write(output)
+	return nil
+}
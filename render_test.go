@@ -0,0 +1,116 @@
+package template
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// createRenderTestStructure 创建包含执行出错页面和500错误页面的模板目录
+func createRenderTestStructure(t *testing.T, with500 bool) string {
+	t.Helper()
+	testDir := t.TempDir()
+	require.NoError(t, createLegacyStructure(testDir))
+
+	brokenDir := filepath.Join(testDir, "pages", "broken")
+	require.NoError(t, os.MkdirAll(brokenDir, 0755))
+	brokenContent := `{{ define "header" }}<title>{{ .title }}</title>{{ end }}
+{{ define "content" }}<h1>{{ .title }}</h1>{{ .user.Name }}{{ end }}`
+	require.NoError(t, os.WriteFile(filepath.Join(brokenDir, "broken.tmpl"), []byte(brokenContent), 0644))
+
+	if with500 {
+		errorContent := `<h1>{{ .title }} {{ .message }}</h1>`
+		require.NoError(t, os.WriteFile(filepath.Join(testDir, "errors", "500.tmpl"), []byte(errorContent), 0644))
+	}
+	return testDir
+}
+
+// TestBufferedRendering 测试缓冲渲染和错误页面回退
+func TestBufferedRendering(t *testing.T) {
+	t.Run("Unbuffered", func(t *testing.T) {
+		engine, err := NewEngine(createRenderTestStructure(t, true), DefaultLoadTemplate, nil)
+		require.NoError(t, err)
+		defer engine.Close()
+		require.NoError(t, engine.Init())
+
+		var buf bytes.Buffer
+		err = engine.RenderPage(&buf, "broken", H{"title": "标题", "user": "guest"})
+		require.Error(t, err)
+		// 未缓冲时输出不完整的页面
+		assert.Contains(t, buf.String(), "<h1>标题</h1>")
+	})
+
+	t.Run("FallbackToErrorPage", func(t *testing.T) {
+		engine, err := NewEngine(createRenderTestStructure(t, true), DefaultLoadTemplate, nil, Buffered(true))
+		require.NoError(t, err)
+		defer engine.Close()
+		require.NoError(t, engine.Init())
+
+		var buf bytes.Buffer
+		err = engine.RenderPage(&buf, "broken", H{"title": "标题", "user": "guest"})
+		require.Error(t, err)
+		assert.NotContains(t, buf.String(), "<h1>标题</h1>")
+		assert.Equal(t, "<h1>500 Internal Server Error</h1>", buf.String())
+	})
+
+	t.Run("FallbackRunsHooks", func(t *testing.T) {
+		engine, err := NewEngine(createRenderTestStructure(t, true), DefaultLoadTemplate, nil, Buffered(true))
+		require.NoError(t, err)
+		defer engine.Close()
+		require.NoError(t, engine.Init())
+
+		var templates []string
+		engine.Use(BeforeRender(func(info *RenderInfo) error {
+			templates = append(templates, info.Template)
+			if info.Kind == KindError {
+				info.Data["title"] = "出错了"
+			}
+			return nil
+		}))
+
+		var buf bytes.Buffer
+		err = engine.RenderPage(&buf, "broken", H{"title": "标题", "user": "guest"})
+		require.Error(t, err)
+		assert.Equal(t, "<h1>出错了 Internal Server Error</h1>", buf.String())
+		assert.Equal(t, []string{"layout.tmpl:pages/broken", "error/500.tmpl"}, templates)
+	})
+
+	t.Run("NoErrorPage", func(t *testing.T) {
+		engine, err := NewEngine(createRenderTestStructure(t, false), DefaultLoadTemplate, nil)
+		require.NoError(t, err)
+		defer engine.Close()
+		require.NoError(t, engine.Init())
+
+		var buf bytes.Buffer
+		err = engine.RenderPage(&buf, "broken", H{"title": "标题", "user": "guest"}, Buffered(true))
+		require.Error(t, err)
+		assert.Zero(t, buf.Len())
+	})
+
+	t.Run("FallbackDisabled", func(t *testing.T) {
+		engine, err := NewEngine(createRenderTestStructure(t, true), DefaultLoadTemplate, nil, Buffered(true), FallbackError(""))
+		require.NoError(t, err)
+		defer engine.Close()
+		require.NoError(t, engine.Init())
+
+		var buf bytes.Buffer
+		err = engine.RenderPage(&buf, "broken", H{"title": "标题", "user": "guest"})
+		require.Error(t, err)
+		assert.Zero(t, buf.Len())
+	})
+
+	t.Run("Success", func(t *testing.T) {
+		engine, err := NewEngine(createRenderTestStructure(t, true), DefaultLoadTemplate, nil, Buffered(true))
+		require.NoError(t, err)
+		defer engine.Close()
+		require.NoError(t, engine.Init())
+
+		var buf bytes.Buffer
+		require.NoError(t, engine.RenderPage(&buf, "sample", H{"title": "正常页面"}))
+		assert.Contains(t, buf.String(), "<h1>正常页面</h1>")
+	})
+}
@@ -2,224 +2,276 @@ package template
 
 import (
 	"embed"
+	"errors"
 	"fmt"
+	"html/template"
 	"io/fs"
+	"os"
 	"path"
 	"path/filepath"
 	"strings"
 )
 
-func loadTemplate(r *Render, templatesDir string, funcMap FuncMap) {
-	// 加载局部页面
-	partials, err := filepath.Glob(filepath.Join(templatesDir, "partials/*.tmpl"))
-	if err != nil {
-		panic(err)
-	}
-	// 加载布局
-	layouts, err := filepath.Glob(filepath.Join(templatesDir, "layouts/*.tmpl"))
-	if err != nil {
-		panic(err)
-	}
-	// 加载错误页面 - 支持分割模板和传统模板
-	errors, err := filepath.Glob(filepath.Join(templatesDir, "errors/*.tmpl"))
-	if err != nil {
-		panic(err)
+// templateSource 模板文件的来源，磁盘目录和嵌入式文件系统都通过 fs.FS 读取
+type templateSource struct {
+	fsys fs.FS    // 读取模板文件的文件系统
+	dir  string   // 模板目录在 fsys 中的路径
+	base string   // 磁盘模板目录，非空时模板中记录的文件路径为磁盘路径，便于错误定位
+	exts []string // 模板文件扩展名，为空时使用默认扩展名
+	// names 文件在模板集合中的名称，如 partials/forms/input.tmpl 为 forms/input，未列出的文件使用基本名称
+	names map[string]string
+}
+
+// dirSource 返回磁盘模板目录的来源
+func dirSource(templatesDir string) templateSource {
+	root := templatesDir
+	if root == "" {
+		root = "."
 	}
-	for _, errPage := range errors {
-		tmplName := fmt.Sprintf("error/%s", filepath.Base(errPage))
-		files := []string{
-			errPage,
-		}
-		files = append(files, partials...)
-		r.AddFromFilesFuncs(tmplName, funcMap, files...)
+	return templateSource{fsys: os.DirFS(root), dir: ".", base: templatesDir}
+}
+
+// filePath 返回 fsys 中的文件在模板和错误信息中记录的路径
+func (s templateSource) filePath(name string) string {
+	if s.base == "" {
+		return name
 	}
+	return filepath.Join(s.base, filepath.FromSlash(name))
+}
 
-	// 加载错误页面文件夹 - 新的分割模板架构
-	var errorDirs []string
-	baseErrorPath := filepath.Join(templatesDir, "errors")
-	err = filepath.WalkDir(baseErrorPath, func(path string, d fs.DirEntry, err error) error {
-		if err != nil {
-			return err
+// parse 逐个解析 files 到 t 中，解析失败时返回 *ParseError 或 LoadErrors
+func (s templateSource) parse(t *template.Template, files []string) (*template.Template, error) {
+	names := make([]string, len(files))
+	paths := make(map[string]string, len(files))
+	for i, file := range files {
+		names[i] = s.filePath(file)
+		paths[names[i]] = file
+	}
+	return parseInto(t, names, func(t *template.Template, file string) (*template.Template, error) {
+		name := s.names[paths[file]]
+		tmpl, err := parseFSFile(t, s.fsys, paths[file], name)
+		var pathErr *fs.PathError
+		if errors.As(err, &pathErr) {
+			pathErr.Path = file
 		}
-		if d.IsDir() && path != baseErrorPath {
-			errorDirs = append(errorDirs, path)
+		if name == "" {
+			return tmpl, err
 		}
-		return nil
-	})
-	if err == nil { // 如果errors目录存在
-		// 查找错误布局
-		errorLayouts, err := filepath.Glob(filepath.Join(templatesDir, "layouts/error.tmpl"))
+		// 以相对路径命名的文件，错误和 ParseName 中的模板名同样还原为文件路径
 		if err != nil {
-			panic(err)
-		}
-		if len(errorLayouts) == 0 {
-			// 如果没有专用错误布局，使用单页布局
-			errorLayouts, err = filepath.Glob(filepath.Join(templatesDir, "layouts/single.tmpl"))
-			if err != nil {
-				panic(err)
+			parseErr := newParseError(err, file)
+			if parseErr.File == name {
+				parseErr.File = file
 			}
+			return nil, parseErr
 		}
+		recordFileName(t, name, file)
+		return tmpl, nil
+	})
+}
 
-		for _, errorDir := range errorDirs {
-			for _, layout := range errorLayouts {
-				errorItems, err := filepath.Glob(filepath.Join(errorDir, "*.tmpl"))
-				if err != nil {
-					panic(err)
-				}
-				if len(errorItems) == 0 {
-					continue
-				}
-				files := []string{
-					layout,
-				}
-				files = append(files, partials...)
-				files = append(files, errorItems...)
-				errorName := errorDir[len(baseErrorPath)+1:]
-				tmplName := fmt.Sprintf("%s:error/%s", filepath.Base(layout), errorName)
-				r.AddFromFilesFuncs(tmplName, funcMap, files...)
-			}
+// templateBuilder 构建渲染器中的模板集合。局部页面和布局只解析一次，
+// 各模板集合复制共享的解析树后再解析自己的文件，避免每个页面重复读取和解析相同的文件
+type templateBuilder struct {
+	src      templateSource
+	funcMap  template.FuncMap
+	partials *template.Template            // 所有局部页面
+	layouts  map[string]*template.Template // 布局和局部页面组成的模板集合，按布局文件缓存
+	errs     LoadErrors
+}
+
+// newTemplateBuilder 解析局部页面并创建构建器，局部页面有误时记录错误后继续，以便一并报告其他文件的错误
+func newTemplateBuilder(src templateSource, funcMap FuncMap, partials []string) *templateBuilder {
+	b := &templateBuilder{
+		src:     src,
+		funcMap: template.FuncMap(funcMap),
+		layouts: make(map[string]*template.Template),
+	}
+	b.partials = template.New("partials").Funcs(b.funcMap)
+	if len(partials) > 0 {
+		if _, err := src.parse(b.partials, partials); err != nil {
+			b.errs.add(err)
+			b.partials = template.New("partials").Funcs(b.funcMap)
 		}
 	}
+	return b
+}
 
-	// 页面文件夹
-	var pageDirs []string
-	basePagePath := filepath.Join(templatesDir, "pages")
-	err = filepath.WalkDir(basePagePath, func(path string, d fs.DirEntry, err error) error {
-		if err != nil {
-			return err
+// base 解析 file 后加入局部页面解析树的副本，与依次解析 file 和所有局部页面的结果相同：
+// 局部页面中的同名块覆盖 file 中的定义
+func (b *templateBuilder) base(file string) (*template.Template, error) {
+	t, err := b.src.parse(template.New(path.Base(file)).Funcs(b.funcMap), []string{file})
+	if err != nil {
+		return nil, err
+	}
+	for _, partial := range b.partials.Templates() {
+		if partial.Tree == nil {
+			continue
 		}
-		if d.IsDir() && path != basePagePath {
-			pageDirs = append(pageDirs, path)
+		// 执行时转义会修改解析树，每个模板集合使用自己的副本
+		if _, err := t.AddParseTree(partial.Name(), partial.Tree.Copy()); err != nil {
+			return nil, err
 		}
-		return nil
-	})
-	if err != nil {
-		panic(err)
 	}
-	for _, pageDir := range pageDirs {
-		for _, layout := range layouts {
-			pageItems, err := filepath.Glob(filepath.Join(pageDir, "*.tmpl"))
-			if err != nil {
-				panic(err)
-			}
-			if len(pageItems) == 0 {
-				continue
-			}
-			files := []string{
-				layout,
-			}
-			files = append(files, partials...)
-			files = append(files, pageItems...)
-			pageName := pageDir[len(basePagePath)+1:]
-			tmplName := fmt.Sprintf("%s:pages/%s", filepath.Base(layout), pageName)
-			r.AddFromFilesFuncs(tmplName, funcMap, files...)
+	return t, nil
+}
+
+// layout 返回布局和局部页面组成的模板集合，每个布局只解析一次。
+// 布局有误时记录错误并返回空的模板集合，使用该布局的页面仍会解析以报告其中的错误
+func (b *templateBuilder) layout(file string) *template.Template {
+	t, ok := b.layouts[file]
+	if !ok {
+		var err error
+		if t, err = b.base(file); err != nil {
+			b.errs.add(err)
+			t = template.New(path.Base(file)).Funcs(b.funcMap)
 		}
+		b.layouts[file] = t
 	}
-	// 加载单页面 - 支持分割模板和传统模板
-	singles, err := filepath.Glob(filepath.Join(templatesDir, "singles/*.tmpl"))
-	if err != nil {
-		panic(err)
+	return t
+}
+
+// add 以 name 添加由 file 和局部页面组成的模板集合，用于传统单页和错误页面
+func (b *templateBuilder) add(r Render, name, file string) {
+	t, err := b.base(file)
+	if err == nil {
+		err = r.Add(name, t)
 	}
-	for _, singlePage := range singles {
-		tmplName := fmt.Sprintf("singles/%s", filepath.Base(singlePage))
-		files := []string{
-			singlePage,
-		}
-		files = append(files, partials...)
-		r.AddFromFilesFuncs(tmplName, funcMap, files...)
+	if err != nil {
+		b.errs.add(err)
 	}
+}
 
-	// 加载单页面文件夹 - 新的分割模板架构
-	var singleDirs []string
-	baseSinglePath := filepath.Join(templatesDir, "singles")
-	err = filepath.WalkDir(baseSinglePath, func(path string, d fs.DirEntry, err error) error {
-		if err != nil {
-			return err
-		}
-		if d.IsDir() && path != baseSinglePath {
-			singleDirs = append(singleDirs, path)
-		}
-		return nil
-	})
-	if err == nil { // 如果singles目录存在
-		// 查找单页布局
-		singleLayouts, err := filepath.Glob(filepath.Join(templatesDir, "layouts/single.tmpl"))
-		if err != nil {
-			panic(err)
-		}
-		if len(singleLayouts) == 0 {
-			// 如果没有专用单页布局，使用默认布局
-			singleLayouts, err = filepath.Glob(filepath.Join(templatesDir, "layouts/layout.tmpl"))
-			if err != nil {
-				panic(err)
-			}
-		}
+// addWithLayout 复制布局的模板集合，解析 files 后以 name 添加到渲染器
+func (b *templateBuilder) addWithLayout(r Render, name, layout string, files []string) {
+	t, err := b.layout(layout).Clone()
+	if err == nil {
+		_, err = b.src.parse(t, files)
+	}
+	if err == nil {
+		err = r.Add(name, t)
+	}
+	if err != nil {
+		b.errs.add(err)
+	}
+}
 
-		for _, singleDir := range singleDirs {
-			for _, layout := range singleLayouts {
-				singleItems, err := filepath.Glob(filepath.Join(singleDir, "*.tmpl"))
-				if err != nil {
-					panic(err)
-				}
-				if len(singleItems) == 0 {
-					continue
-				}
-				files := []string{
-					layout,
-				}
-				files = append(files, partials...)
-				files = append(files, singleItems...)
-				singleName := singleDir[len(baseSinglePath)+1:]
-				tmplName := fmt.Sprintf("%s:singles/%s", filepath.Base(layout), singleName)
-				r.AddFromFilesFuncs(tmplName, funcMap, files...)
-			}
-		}
+// parseFSFile 读取并解析单个模板文件，文件以 name 命名，name 为空时使用文件的基本名称。
+// 与 template.ParseFS 相同，名称与 t 相同时解析到 t 中，但不把文件名当作通配符
+func parseFSFile(t *template.Template, fsys fs.FS, file, name string) (*template.Template, error) {
+	b, err := fs.ReadFile(fsys, file)
+	if err != nil {
+		return nil, err
 	}
+	if name == "" {
+		name = path.Base(file)
+	}
+	tmpl := t
+	if name != t.Name() {
+		tmpl = t.New(name)
+	}
+	return tmpl.Parse(string(b))
 }
 
-// DefaultLoadTemplate ...
+// DefaultLoadTemplate 加载模板目录，出错时panic，返回错误的版本见 LoadTemplate
 func DefaultLoadTemplate(templatesDir string, funcMap FuncMap) Render {
-	r := NewRender()
-	loadTemplate(&r, templatesDir, funcMap)
-	return r
+	return LoadTemplateFuncE(LoadTemplate).Must()(templatesDir, funcMap)
 }
 
+// DefaultLoadTemplateWithEmbedFS 加载嵌入式模板目录，出错时panic，返回错误的版本见 LoadTemplateWithEmbedFS
 func DefaultLoadTemplateWithEmbedFS(tmplFS *embed.FS, tmplFSSUbDir string, funcMap FuncMap) Render {
+	return LoadEmbedFSTemplateFuncE(LoadTemplateWithEmbedFS).Must()(tmplFS, tmplFSSUbDir, funcMap)
+}
+
+// DefaultLoadTemplateFS 从文件系统加载模板目录，出错时panic，返回错误的版本见 LoadTemplateFS
+func DefaultLoadTemplateFS(fsys fs.FS, dir string, funcMap FuncMap) Render {
+	return LoadFSTemplateFuncE(LoadTemplateFS).Must()(fsys, dir, funcMap)
+}
+
+// LoadTemplate 加载模板目录，所有出错的文件汇总为一个 LoadErrors 返回
+func LoadTemplate(templatesDir string, funcMap FuncMap) (Render, error) {
+	return loadRender(dirSource(templatesDir), funcMap)
+}
+
+// LoadTemplateWithEmbedFS 加载嵌入式模板目录，所有出错的文件汇总为一个 LoadErrors 返回
+func LoadTemplateWithEmbedFS(tmplFS *embed.FS, tmplFSSUbDir string, funcMap FuncMap) (Render, error) {
+	return loadRender(templateSource{fsys: tmplFS, dir: tmplFSSUbDir}, funcMap)
+}
+
+// LoadTemplateFS 从任意文件系统（os.DirFS、fstest.MapFS、zip.Reader 等）加载 dir 目录中的模板，
+// dir 为 "." 时加载文件系统的根目录，所有出错的文件汇总为一个 LoadErrors 返回
+func LoadTemplateFS(fsys fs.FS, dir string, funcMap FuncMap) (Render, error) {
+	return loadRender(templateSource{fsys: fsys, dir: dir}, funcMap)
+}
+
+// LoadTemplateWithExtensions 返回只加载 exts 扩展名模板文件的 LoadTemplate，前导点可以省略，
+// 与引擎的 Extensions 选项配合使用：NewEngine(dir, LoadTemplateWithExtensions(".html").Must(), funcMap, Extensions(".html"))
+func LoadTemplateWithExtensions(exts ...string) LoadTemplateFuncE {
+	exts = normalizeExtensions(exts)
+	return func(templatesDir string, funcMap FuncMap) (Render, error) {
+		src := dirSource(templatesDir)
+		src.exts = exts
+		return loadRender(src, funcMap)
+	}
+}
+
+// LoadTemplateWithEmbedFSExtensions 返回只加载 exts 扩展名模板文件的 LoadTemplateWithEmbedFS
+func LoadTemplateWithEmbedFSExtensions(exts ...string) LoadEmbedFSTemplateFuncE {
+	exts = normalizeExtensions(exts)
+	return func(tmplFS *embed.FS, tmplFSSUbDir string, funcMap FuncMap) (Render, error) {
+		return loadRender(templateSource{fsys: tmplFS, dir: tmplFSSUbDir, exts: exts}, funcMap)
+	}
+}
+
+// LoadTemplateFSWithExtensions 返回只加载 exts 扩展名模板文件的 LoadTemplateFS
+func LoadTemplateFSWithExtensions(exts ...string) LoadFSTemplateFuncE {
+	exts = normalizeExtensions(exts)
+	return func(fsys fs.FS, dir string, funcMap FuncMap) (Render, error) {
+		return loadRender(templateSource{fsys: fsys, dir: dir, exts: exts}, funcMap)
+	}
+}
+
+// loadRender 从 src 加载模板到新的渲染器
+func loadRender(src templateSource, funcMap FuncMap) (Render, error) {
 	r := NewRender()
-	loadTemplateWithEmbedFS(&r, tmplFS, tmplFSSUbDir, funcMap)
-	return r
+	if err := loadTemplateFS(&r, src, funcMap); err != nil {
+		return nil, err
+	}
+	return r, nil
 }
 
-func loadTemplateWithEmbedFS(r *Render, tmplFS *embed.FS, tmplFSSUbDir string, funcMap FuncMap) {
-	// embed.FS 总是使用正斜杠路径，使用 path 包而不是 filepath 包
-	// 加载局部页面
-	partials, err := fs.Glob(tmplFS, path.Join(tmplFSSUbDir, "partials/*.tmpl"))
+// loadTemplateFS 从文件系统加载模板目录，收集所有出错的文件后一并返回。局部页面和布局只解析一次，见 templateBuilder
+func loadTemplateFS(r *Render, src templateSource, funcMap FuncMap) error {
+	fsys, dir := src.fsys, src.dir
+	exts := orDefaultExtensions(src.exts)
+	src.names = make(map[string]string)
+
+	// fs.FS 总是使用正斜杠路径，使用 path 包而不是 filepath 包
+	// 加载局部页面，包括子目录中以相对路径命名的局部页面
+	partials, err := globPartials(fsys, path.Join(dir, "partials"), exts, src.names)
 	if err != nil {
-		panic(err)
+		return err
 	}
+	builder := newTemplateBuilder(src, funcMap, partials)
 	// 加载布局
-	layouts, err := fs.Glob(tmplFS, path.Join(tmplFSSUbDir, "layouts/*.tmpl"))
+	layouts, err := globTemplates(fsys, path.Join(dir, "layouts"), exts)
 	if err != nil {
-		panic(err)
+		return err
 	}
 	// 加载错误页面 - 支持分割模板和传统模板
-	errors, err := fs.Glob(tmplFS, path.Join(tmplFSSUbDir, "errors/*.tmpl"))
+	errors, err := globTemplates(fsys, path.Join(dir, "errors"), exts)
 	if err != nil {
-		panic(err)
+		return err
 	}
 	for _, errPage := range errors {
 		tmplName := fmt.Sprintf("error/%s", path.Base(errPage))
-		files := []string{
-			errPage,
-		}
-		files = append(files, partials...)
-		r.AddFromFSFuncs(tmplName, funcMap, tmplFS, files...)
+		builder.add(*r, tmplName, errPage)
 	}
 
 	// 加载错误页面文件夹 - 新的分割模板架构
 	var errorDirs []string
-	baseErrorPath := path.Join(tmplFSSUbDir, "errors")
-	err = fs.WalkDir(tmplFS, baseErrorPath, func(p string, d fs.DirEntry, err error) error {
+	baseErrorPath := path.Join(dir, "errors")
+	err = fs.WalkDir(fsys, baseErrorPath, func(p string, d fs.DirEntry, err error) error {
 		if err != nil {
 			return err
 		}
@@ -230,43 +282,38 @@ func loadTemplateWithEmbedFS(r *Render, tmplFS *embed.FS, tmplFSSUbDir string, f
 	})
 	if err == nil { // 如果errors目录存在
 		// 查找错误布局
-		errorLayouts, err := fs.Glob(tmplFS, path.Join(tmplFSSUbDir, "layouts/error.tmpl"))
+		errorLayouts, err := findLayout(fsys, dir, "error", exts)
 		if err != nil {
-			panic(err)
+			return err
 		}
 		if len(errorLayouts) == 0 {
 			// 如果没有专用错误布局，使用单页布局
-			errorLayouts, err = fs.Glob(tmplFS, path.Join(tmplFSSUbDir, "layouts/single.tmpl"))
+			errorLayouts, err = findLayout(fsys, dir, "single", exts)
 			if err != nil {
-				panic(err)
+				return err
 			}
 		}
 
 		for _, errorDir := range errorDirs {
 			for _, layout := range errorLayouts {
-				errorItems, err := fs.Glob(tmplFS, path.Join(errorDir, "*.tmpl"))
+				errorItems, err := globTemplates(fsys, errorDir, exts)
 				if err != nil {
-					panic(err)
+					return err
 				}
 				if len(errorItems) == 0 {
 					continue
 				}
-				files := []string{
-					layout,
-				}
-				files = append(files, partials...)
-				files = append(files, errorItems...)
 				errorName := strings.TrimPrefix(errorDir, baseErrorPath+"/")
 				tmplName := fmt.Sprintf("%s:error/%s", path.Base(layout), errorName)
-				r.AddFromFSFuncs(tmplName, funcMap, tmplFS, files...)
+				builder.addWithLayout(*r, tmplName, layout, errorItems)
 			}
 		}
 	}
 
 	// 页面文件夹
 	var pageDirs []string
-	basePagePath := path.Join(tmplFSSUbDir, "pages")
-	err = fs.WalkDir(tmplFS, basePagePath, func(p string, d fs.DirEntry, err error) error {
+	basePagePath := path.Join(dir, "pages")
+	err = fs.WalkDir(fsys, basePagePath, func(p string, d fs.DirEntry, err error) error {
 		if err != nil {
 			return err
 		}
@@ -276,45 +323,47 @@ func loadTemplateWithEmbedFS(r *Render, tmplFS *embed.FS, tmplFSSUbDir string, f
 		return nil
 	})
 	if err != nil {
-		panic(err)
+		return err
+	}
+	// 每个页面目录只与清单声明的、专用的或通用的布局组合
+	allowed, err := newPageLayouts(src, basePagePath, layouts, pageDirs)
+	if err != nil {
+		builder.errs.add(err)
 	}
 	for _, pageDir := range pageDirs {
-		for _, layout := range layouts {
-			pageItems, err := fs.Glob(tmplFS, path.Join(pageDir, "*.tmpl"))
-			if err != nil {
-				panic(err)
-			}
-			if len(pageItems) == 0 {
-				continue
-			}
-			files := []string{
-				layout,
+		pageItems, err := globTemplates(fsys, pageDir, exts)
+		if err != nil {
+			return err
+		}
+		// 只有页面局部模板的目录不是页面
+		if !hasPageTemplate(pageItems) {
+			continue
+		}
+		for _, item := range pageItems {
+			if isLocalPartial(item) {
+				src.names[item] = templateStem(path.Base(item))
 			}
-			files = append(files, partials...)
-			files = append(files, pageItems...)
-			pageName := strings.TrimPrefix(pageDir, basePagePath+"/")
+		}
+		pageName := strings.TrimPrefix(pageDir, basePagePath+"/")
+		for _, layout := range allowed.layoutsFor(pageName) {
 			tmplName := fmt.Sprintf("%s:pages/%s", path.Base(layout), pageName)
-			r.AddFromFSFuncs(tmplName, funcMap, tmplFS, files...)
+			builder.addWithLayout(*r, tmplName, layout, pageItems)
 		}
 	}
 	// 加载单页面 - 支持分割模板和传统模板
-	singles, err := fs.Glob(tmplFS, path.Join(tmplFSSUbDir, "singles/*.tmpl"))
+	singles, err := globTemplates(fsys, path.Join(dir, "singles"), exts)
 	if err != nil {
-		panic(err)
+		return err
 	}
 	for _, singlePage := range singles {
 		tmplName := fmt.Sprintf("singles/%s", path.Base(singlePage))
-		files := []string{
-			singlePage,
-		}
-		files = append(files, partials...)
-		r.AddFromFSFuncs(tmplName, funcMap, tmplFS, files...)
+		builder.add(*r, tmplName, singlePage)
 	}
 
 	// 加载单页面文件夹 - 新的分割模板架构
 	var singleDirs []string
-	baseSinglePath := path.Join(tmplFSSUbDir, "singles")
-	err = fs.WalkDir(tmplFS, baseSinglePath, func(p string, d fs.DirEntry, err error) error {
+	baseSinglePath := path.Join(dir, "singles")
+	err = fs.WalkDir(fsys, baseSinglePath, func(p string, d fs.DirEntry, err error) error {
 		if err != nil {
 			return err
 		}
@@ -325,36 +374,76 @@ func loadTemplateWithEmbedFS(r *Render, tmplFS *embed.FS, tmplFSSUbDir string, f
 	})
 	if err == nil { // 如果singles目录存在
 		// 查找单页布局
-		singleLayouts, err := fs.Glob(tmplFS, path.Join(tmplFSSUbDir, "layouts/single.tmpl"))
+		singleLayouts, err := findLayout(fsys, dir, "single", exts)
 		if err != nil {
-			panic(err)
+			return err
 		}
 		if len(singleLayouts) == 0 {
 			// 如果没有专用单页布局，使用默认布局
-			singleLayouts, err = fs.Glob(tmplFS, path.Join(tmplFSSUbDir, "layouts/layout.tmpl"))
+			singleLayouts, err = findLayout(fsys, dir, "layout", exts)
 			if err != nil {
-				panic(err)
+				return err
 			}
 		}
 
 		for _, singleDir := range singleDirs {
 			for _, layout := range singleLayouts {
-				singleItems, err := fs.Glob(tmplFS, path.Join(singleDir, "*.tmpl"))
+				singleItems, err := globTemplates(fsys, singleDir, exts)
 				if err != nil {
-					panic(err)
+					return err
 				}
 				if len(singleItems) == 0 {
 					continue
 				}
-				files := []string{
-					layout,
-				}
-				files = append(files, partials...)
-				files = append(files, singleItems...)
 				singleName := strings.TrimPrefix(singleDir, baseSinglePath+"/")
 				tmplName := fmt.Sprintf("%s:singles/%s", path.Base(layout), singleName)
-				r.AddFromFSFuncs(tmplName, funcMap, tmplFS, files...)
+				builder.addWithLayout(*r, tmplName, layout, singleItems)
 			}
 		}
 	}
+
+	return builder.errs.err()
+}
+
+// globPartials 递归返回 partials 目录中的模板文件，目录不存在时返回空。
+// 子目录中的文件以相对路径（不含扩展名）命名并记录到 names，如 partials/forms/input.tmpl 为 forms/input
+func globPartials(fsys fs.FS, dir string, exts []string, names map[string]string) ([]string, error) {
+	var partials []string
+	err := fs.WalkDir(fsys, dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !hasTemplateExtension(p, exts) {
+			return nil
+		}
+		partials = append(partials, p)
+		if rel := strings.TrimPrefix(p, dir+"/"); strings.Contains(rel, "/") {
+			names[p] = templateStem(rel)
+		}
+		return nil
+	})
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	return partials, err
+}
+
+// isLocalPartial 检查页面目录中的文件是否为只对该页面可见的局部模板，如 pages/posts/list/_item.tmpl
+func isLocalPartial(file string) bool {
+	return strings.HasPrefix(path.Base(file), "_")
+}
+
+// hasPageTemplate 检查页面目录的文件中是否有局部模板以外的模板文件
+func hasPageTemplate(files []string) bool {
+	for _, file := range files {
+		if !isLocalPartial(file) {
+			return true
+		}
+	}
+	return false
+}
+
+// templateStem 返回去掉扩展名的模板路径，作为局部模板的名称
+func templateStem(file string) string {
+	return strings.TrimSuffix(file, path.Ext(file))
 }
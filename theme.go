@@ -3,12 +3,15 @@ package template
 import (
 	"embed"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"html/template"
 	"io/fs"
 	"os"
+	"path"
 	"path/filepath"
 	"strings"
+	"sync"
 )
 
 // Theme 表示一个主题
@@ -16,7 +19,7 @@ type Theme struct {
 	Name       string        `json:"name"`        // 主题名称
 	Path       string        `json:"path"`        // 主题路径
 	IsDefault  bool          `json:"is_default"`  // 是否为默认主题
-	IsEmbedded bool          `json:"is_embedded"` // 是否为嵌入式主题
+	IsEmbedded bool          `json:"is_embedded"` // 是否来自嵌入式文件系统或其他 fs.FS（不支持文件监听）
 	Metadata   ThemeMetadata `json:"metadata"`    // 主题元数据
 }
 
@@ -50,14 +53,29 @@ type ThemeManager interface {
 	ReloadCurrentTheme() error
 }
 
+// ThemeProvider 主题管理器可选实现的接口，实现后引擎可以获取主题目录以监听模板文件
+type ThemeProvider interface {
+	// GetTheme 获取指定主题的信息，不会重新加载模板
+	GetTheme(name string) (*Theme, error)
+}
+
+// ThemeRenderProvider 主题管理器可选实现的接口，实现后引擎可以按 SetTheme 选择非当前主题渲染
+type ThemeRenderProvider interface {
+	// GetThemeRender 获取指定主题的渲染器，不切换当前主题
+	GetThemeRender(name string) (Render, error)
+}
+
 // ThemeDiscovery 主题发现器
 type ThemeDiscovery struct {
 	baseDir       string
 	embedFS       *embed.FS
 	subDir        string
+	fsys          fs.FS // 主题所在的文件系统，为空时使用磁盘目录 baseDir
 	funcMap       FuncMap
 	loadFunc      LoadTemplateFunc
 	loadEmbedFunc LoadEmbedFSTemplateFunc
+	loadFSFunc    LoadFSTemplateFunc
+	exts          []string // 校验主题时识别的模板文件扩展名，为空时识别 .tmpl、.html、.gohtml 和 .tpl
 }
 
 // DiscoverMode 发现模式
@@ -130,52 +148,98 @@ func NewThemeDiscovery(baseDir string, funcMap FuncMap, loadFunc LoadTemplateFun
 
 // NewThemeDiscoveryWithEmbedFS 创建嵌入式文件系统的主题发现器
 func NewThemeDiscoveryWithEmbedFS(embedFS *embed.FS, subDir string, funcMap FuncMap, loadEmbedFunc LoadEmbedFSTemplateFunc) *ThemeDiscovery {
-	return &ThemeDiscovery{
+	td := &ThemeDiscovery{
 		embedFS:       embedFS,
 		subDir:        subDir,
 		funcMap:       funcMap,
 		loadEmbedFunc: loadEmbedFunc,
 	}
+	if embedFS != nil {
+		td.fsys = embedFS
+	}
+	if loadEmbedFunc != nil {
+		td.loadFSFunc = embedLoadFunc(embedFS, loadEmbedFunc)
+	}
+	return td
 }
 
-// DetectMode 检测目录模式
-func (td *ThemeDiscovery) DetectMode() (DiscoverMode, error) {
-	if td.embedFS != nil {
-		return td.detectModeEmbedFS()
+// NewThemeDiscoveryWithFS 创建任意文件系统的主题发现器，subDir 为主题根目录在 fsys 中的路径，"." 表示根目录
+func NewThemeDiscoveryWithFS(fsys fs.FS, subDir string, funcMap FuncMap, loadFunc LoadFSTemplateFunc) *ThemeDiscovery {
+	return &ThemeDiscovery{
+		fsys:       fsys,
+		subDir:     subDir,
+		funcMap:    funcMap,
+		loadFSFunc: loadFunc,
 	}
-	return td.detectModeFileSystem()
 }
 
-// detectModeFileSystem 检测文件系统模式
-func (td *ThemeDiscovery) detectModeFileSystem() (DiscoverMode, error) {
-	// 检查是否存在传统结构
-	hasLegacyStructure := td.hasLegacyStructure()
+// SetExtensions 设置校验主题时识别的模板文件扩展名，前导点可以省略，应与加载函数使用的扩展名一致
+func (td *ThemeDiscovery) SetExtensions(exts ...string) {
+	td.exts = normalizeExtensions(exts)
+}
 
-	// 检查是否存在主题子目录
-	hasThemeSubdirs, err := td.hasThemeSubdirectories()
-	if err != nil {
-		return ModeLegacy, err
+// extensions 返回校验主题时识别的扩展名，未设置时识别 .tmpl、.html、.gohtml 和 .tpl
+func (td *ThemeDiscovery) extensions() []string {
+	if len(td.exts) > 0 {
+		return td.exts
 	}
+	return defaultWatchExtensions
+}
 
-	if hasLegacyStructure && !hasThemeSubdirs {
-		return ModeLegacy, nil
+// files 返回主题所在的文件系统，磁盘目录使用以 baseDir 为根的 os.DirFS
+func (td *ThemeDiscovery) files() fs.FS {
+	if td.fsys != nil {
+		return td.fsys
 	}
+	return os.DirFS(td.baseDir)
+}
 
-	if hasThemeSubdirs {
-		return ModeMultiTheme, nil
+// fsPath 将主题路径转换为 files() 中的路径。磁盘目录的主题路径为操作系统路径，
+// 转换为相对 baseDir 的正斜杠路径，baseDir 之外的路径不满足 fs.ValidPath，访问时返回错误
+func (td *ThemeDiscovery) fsPath(name string) string {
+	if td.fsys != nil {
+		return name
 	}
+	rel, err := filepath.Rel(td.baseDir, name)
+	if err != nil {
+		return name
+	}
+	return filepath.ToSlash(rel)
+}
 
-	// 如果都没有，默认为传统模式
-	return ModeLegacy, nil
+// readDir 读取主题路径 name 对应的目录
+func (td *ThemeDiscovery) readDir(name string) ([]fs.DirEntry, error) {
+	return fs.ReadDir(td.files(), td.fsPath(name))
 }
 
-// detectModeEmbedFS 检测嵌入式文件系统模式
-func (td *ThemeDiscovery) detectModeEmbedFS() (DiscoverMode, error) {
+// readFile 读取主题路径 name 对应的文件
+func (td *ThemeDiscovery) readFile(name string) ([]byte, error) {
+	return fs.ReadFile(td.files(), td.fsPath(name))
+}
+
+// root 返回主题根目录的主题路径，即磁盘目录或文件系统中的子目录
+func (td *ThemeDiscovery) root() string {
+	if td.fsys != nil {
+		return td.subDir
+	}
+	return td.baseDir
+}
+
+// join 拼接主题路径，fs.FS 使用正斜杠路径，磁盘目录使用操作系统路径
+func (td *ThemeDiscovery) join(elem ...string) string {
+	if td.fsys != nil {
+		return path.Join(elem...)
+	}
+	return filepath.Join(elem...)
+}
+
+// DetectMode 检测目录模式
+func (td *ThemeDiscovery) DetectMode() (DiscoverMode, error) {
 	// 检查是否存在传统结构
-	hasLegacyStructure := td.hasLegacyStructureEmbedFS()
+	hasLegacyStructure := td.hasLegacyStructure()
 
 	// 检查是否存在主题子目录
-	hasThemeSubdirs, err := td.hasThemeSubdirectoriesEmbedFS()
+	hasThemeSubdirs, err := td.hasThemeSubdirectories()
 	if err != nil {
 		return ModeLegacy, err
 	}
@@ -197,21 +261,7 @@ func (td *ThemeDiscovery) hasLegacyStructure() bool {
 	requiredDirs := []string{"layouts", "pages", "singles", "errors"}
 
 	for _, dir := range requiredDirs {
-		dirPath := filepath.Join(td.baseDir, dir)
-		if !td.dirExists(dirPath) {
-			return false
-		}
-	}
-	return true
-}
-
-// hasLegacyStructureEmbedFS 检查嵌入式文件系统是否存在传统结构
-func (td *ThemeDiscovery) hasLegacyStructureEmbedFS() bool {
-	requiredDirs := []string{"layouts", "pages", "singles", "errors"}
-
-	for _, dir := range requiredDirs {
-		dirPath := filepath.Join(td.subDir, dir)
-		if !td.dirExistsEmbedFS(dirPath) {
+		if !td.dirExists(td.join(td.root(), dir)) {
 			return false
 		}
 	}
@@ -220,14 +270,14 @@ func (td *ThemeDiscovery) hasLegacyStructureEmbedFS() bool {
 
 // hasThemeSubdirectories 检查是否存在主题子目录
 func (td *ThemeDiscovery) hasThemeSubdirectories() (bool, error) {
-	entries, err := os.ReadDir(td.baseDir)
+	entries, err := td.readDir(td.root())
 	if err != nil {
 		return false, err
 	}
 
 	for _, entry := range entries {
 		if entry.IsDir() {
-			themePath := filepath.Join(td.baseDir, entry.Name())
+			themePath := td.join(td.root(), entry.Name())
 			if td.isValidThemeDirectory(themePath) {
 				return true, nil
 			}
@@ -236,46 +286,17 @@ func (td *ThemeDiscovery) hasThemeSubdirectories() (bool, error) {
 	return false, nil
 }
 
-// hasThemeSubdirectoriesEmbedFS 检查嵌入式文件系统是否存在主题子目录
-func (td *ThemeDiscovery) hasThemeSubdirectoriesEmbedFS() (bool, error) {
-	entries, err := fs.ReadDir(td.embedFS, td.subDir)
-	if err != nil {
-		return false, err
-	}
-
-	for _, entry := range entries {
-		if entry.IsDir() {
-			themePath := filepath.Join(td.subDir, entry.Name())
-			if td.isValidThemeDirectoryEmbedFS(themePath) {
-				return true, nil
-			}
-		}
-	}
-	return false, nil
-}
-
 // dirExists 检查目录是否存在
-func (td *ThemeDiscovery) dirExists(path string) bool {
-	info, err := os.Stat(path)
+func (td *ThemeDiscovery) dirExists(dirPath string) bool {
+	info, err := fs.Stat(td.files(), td.fsPath(dirPath))
 	return err == nil && info.IsDir()
 }
 
-// dirExistsEmbedFS 检查嵌入式文件系统中目录是否存在
-func (td *ThemeDiscovery) dirExistsEmbedFS(path string) bool {
-	_, err := fs.Stat(td.embedFS, path)
-	return err == nil
-}
-
 // isValidThemeDirectory 检查是否为有效的主题目录
 func (td *ThemeDiscovery) isValidThemeDirectory(themePath string) bool {
 	return td.ValidateTheme(themePath) == nil
 }
 
-// isValidThemeDirectoryEmbedFS 检查嵌入式文件系统中是否为有效的主题目录
-func (td *ThemeDiscovery) isValidThemeDirectoryEmbedFS(themePath string) bool {
-	return td.ValidateTheme(themePath) == nil
-}
-
 // ValidateTheme 验证主题的完整性和有效性
 func (td *ThemeDiscovery) ValidateTheme(themePath string) error {
 	themeName := filepath.Base(themePath)
@@ -313,67 +334,38 @@ func (td *ThemeDiscovery) ValidateTheme(themePath string) error {
 	return nil
 }
 
-// validateThemeStructure 验证主题目录结构
+// validateThemeStructure 验证主题目录结构，partials 目录是可选的
 func (td *ThemeDiscovery) validateThemeStructure(themePath string) error {
 	requiredDirs := []string{"layouts", "pages", "singles", "errors"}
 
 	for _, dir := range requiredDirs {
-		var dirPath string
-		var exists bool
-
-		if td.embedFS != nil {
-			dirPath = filepath.Join(themePath, dir)
-			exists = td.dirExistsEmbedFS(dirPath)
-		} else {
-			dirPath = filepath.Join(themePath, dir)
-			exists = td.dirExists(dirPath)
-		}
-
-		if !exists {
+		if !td.dirExists(td.join(themePath, dir)) {
 			return fmt.Errorf("required directory '%s' not found", dir)
 		}
 	}
 
-	// 验证partials目录（可选但推荐）
-	var partialsPath string
-	if td.embedFS != nil {
-		partialsPath = filepath.Join(themePath, "partials")
-		if !td.dirExistsEmbedFS(partialsPath) {
-			// partials目录是可选的，只记录警告
-		}
-	} else {
-		partialsPath = filepath.Join(themePath, "partials")
-		if !td.dirExists(partialsPath) {
-			// partials目录是可选的，只记录警告
-		}
-	}
-
 	return nil
 }
 
 // validateRequiredTemplates 验证必需的模板文件
 func (td *ThemeDiscovery) validateRequiredTemplates(themePath string) error {
 	// 检查layouts目录中是否至少有一个布局文件
-	layoutsPath := filepath.Join(themePath, "layouts")
-	if err := td.validateDirectoryHasTemplates(layoutsPath, "layouts"); err != nil {
+	if err := td.validateDirectoryHasTemplates(td.join(themePath, "layouts"), "layouts"); err != nil {
 		return err
 	}
 
 	// 检查pages目录中是否有模板文件（可以在子目录中）
-	pagesPath := filepath.Join(themePath, "pages")
-	if err := td.validatePagesDirectory(pagesPath); err != nil {
+	if err := td.validatePagesDirectory(td.join(themePath, "pages")); err != nil {
 		return err
 	}
 
 	// 检查singles目录中是否有模板文件
-	singlesPath := filepath.Join(themePath, "singles")
-	if err := td.validateDirectoryHasTemplates(singlesPath, "singles"); err != nil {
+	if err := td.validateDirectoryHasTemplates(td.join(themePath, "singles"), "singles"); err != nil {
 		return err
 	}
 
 	// 检查errors目录中是否有模板文件
-	errorsPath := filepath.Join(themePath, "errors")
-	if err := td.validateDirectoryHasTemplates(errorsPath, "errors"); err != nil {
+	if err := td.validateDirectoryHasTemplates(td.join(themePath, "errors"), "errors"); err != nil {
 		return err
 	}
 
@@ -382,15 +374,7 @@ func (td *ThemeDiscovery) validateRequiredTemplates(themePath string) error {
 
 // validateDirectoryHasTemplates 验证目录中是否包含模板文件
 func (td *ThemeDiscovery) validateDirectoryHasTemplates(dirPath, dirName string) error {
-	var entries []fs.DirEntry
-	var err error
-
-	if td.embedFS != nil {
-		entries, err = fs.ReadDir(td.embedFS, dirPath)
-	} else {
-		entries, err = os.ReadDir(dirPath)
-	}
-
+	entries, err := td.readDir(dirPath)
 	if err != nil {
 		return fmt.Errorf("cannot read %s directory: %w", dirName, err)
 	}
@@ -399,47 +383,31 @@ func (td *ThemeDiscovery) validateDirectoryHasTemplates(dirPath, dirName string)
 
 	// 检查直接在目录中的模板文件
 	for _, entry := range entries {
-		if !entry.IsDir() {
-			// 检查是否为模板文件（.tmpl, .html, .gohtml等）
-			name := entry.Name()
-			if td.isTemplateFile(name) {
-				hasTemplates = true
-				break
-			}
+		if !entry.IsDir() && td.isTemplateFile(entry.Name()) {
+			hasTemplates = true
+			break
 		}
 	}
 
 	// 如果直接在目录中没有找到模板，检查子目录（支持分割模板架构）
 	if !hasTemplates {
 		for _, entry := range entries {
-			if entry.IsDir() {
-				var subDirPath string
-				if td.embedFS != nil {
-					subDirPath = filepath.Join(dirPath, entry.Name())
-				} else {
-					subDirPath = filepath.Join(dirPath, entry.Name())
-				}
-
-				// 递归检查子目录中是否有模板文件
-				var subEntries []fs.DirEntry
-				if td.embedFS != nil {
-					subEntries, err = fs.ReadDir(td.embedFS, subDirPath)
-				} else {
-					subEntries, err = os.ReadDir(subDirPath)
-				}
-
-				if err == nil {
-					for _, subEntry := range subEntries {
-						if !subEntry.IsDir() && td.isTemplateFile(subEntry.Name()) {
-							hasTemplates = true
-							break
-						}
-					}
-					if hasTemplates {
-						break
-					}
+			if !entry.IsDir() {
+				continue
+			}
+			subEntries, err := td.readDir(td.join(dirPath, entry.Name()))
+			if err != nil {
+				continue
+			}
+			for _, subEntry := range subEntries {
+				if !subEntry.IsDir() && td.isTemplateFile(subEntry.Name()) {
+					hasTemplates = true
+					break
 				}
 			}
+			if hasTemplates {
+				break
+			}
 		}
 	}
 
@@ -452,15 +420,7 @@ func (td *ThemeDiscovery) validateDirectoryHasTemplates(dirPath, dirName string)
 
 // validatePagesDirectory 验证pages目录（可以包含子目录中的模板）
 func (td *ThemeDiscovery) validatePagesDirectory(pagesPath string) error {
-	var entries []fs.DirEntry
-	var err error
-
-	if td.embedFS != nil {
-		entries, err = fs.ReadDir(td.embedFS, pagesPath)
-	} else {
-		entries, err = os.ReadDir(pagesPath)
-	}
-
+	entries, err := td.readDir(pagesPath)
 	if err != nil {
 		return fmt.Errorf("cannot read pages directory: %w", err)
 	}
@@ -469,31 +429,22 @@ func (td *ThemeDiscovery) validatePagesDirectory(pagesPath string) error {
 
 	// 检查直接在pages目录中的模板文件
 	for _, entry := range entries {
-		if !entry.IsDir() {
-			name := entry.Name()
-			if td.isTemplateFile(name) {
-				hasTemplates = true
-				break
-			}
+		if !entry.IsDir() && td.isTemplateFile(entry.Name()) {
+			hasTemplates = true
+			break
 		}
 	}
 
 	// 如果直接在pages目录中没有找到模板，检查子目录
 	if !hasTemplates {
 		for _, entry := range entries {
-			if entry.IsDir() {
-				var subDirPath string
-				if td.embedFS != nil {
-					subDirPath = filepath.Join(pagesPath, entry.Name())
-				} else {
-					subDirPath = filepath.Join(pagesPath, entry.Name())
-				}
-
-				// 检查子目录中是否有模板文件
-				if err := td.validateDirectoryHasTemplates(subDirPath, fmt.Sprintf("pages/%s", entry.Name())); err == nil {
-					hasTemplates = true
-					break
-				}
+			if !entry.IsDir() {
+				continue
+			}
+			subDirPath := td.join(pagesPath, entry.Name())
+			if err := td.validateDirectoryHasTemplates(subDirPath, fmt.Sprintf("pages/%s", entry.Name())); err == nil {
+				hasTemplates = true
+				break
 			}
 		}
 	}
@@ -505,36 +456,18 @@ func (td *ThemeDiscovery) validatePagesDirectory(pagesPath string) error {
 	return nil
 }
 
-// isTemplateFile 检查文件是否为模板文件
+// isTemplateFile 检查文件是否使用模板扩展名，见 SetExtensions
 func (td *ThemeDiscovery) isTemplateFile(filename string) bool {
-	ext := filepath.Ext(filename)
-	templateExts := []string{".tmpl", ".html", ".gohtml", ".tpl"}
-
-	for _, validExt := range templateExts {
-		if ext == validExt {
-			return true
-		}
-	}
-	return false
+	return hasTemplateExtension(filename, td.extensions())
 }
 
 // validateThemeConfig 验证主题配置文件
 func (td *ThemeDiscovery) validateThemeConfig(themePath string) error {
-	var configPath string
-	var data []byte
-	var err error
-
-	if td.embedFS != nil {
-		configPath = filepath.Join(themePath, "theme.json")
-		data, err = fs.ReadFile(td.embedFS, configPath)
-	} else {
-		configPath = filepath.Join(themePath, "theme.json")
-		data, err = os.ReadFile(configPath)
-	}
+	data, err := td.readFile(td.join(themePath, "theme.json"))
 
 	// 如果theme.json不存在，这是可以接受的
 	if err != nil {
-		if os.IsNotExist(err) || err == fs.ErrNotExist {
+		if errors.Is(err, fs.ErrNotExist) {
 			return nil
 		}
 		return fmt.Errorf("cannot read theme.json: %w", err)
@@ -575,16 +508,19 @@ func (td *ThemeDiscovery) validateThemeConfig(themePath string) error {
 	return nil
 }
 
-// DefaultThemeManager 默认主题管理器实现
+// DefaultThemeManager 默认主题管理器实现，可安全地并发使用
 type DefaultThemeManager struct {
+	mu            sync.RWMutex // 保护下列可变状态，写操作（发现、加载、切换）互斥执行
 	discovery     *ThemeDiscovery
 	themes        map[string]*Theme
 	currentTheme  string
 	defaultTheme  string
-	render        Render
+	render        Render            // 当前主题的渲染器
+	renders       map[string]Render // 所有已加载主题的渲染器，按主题名称索引
 	funcMap       FuncMap
 	loadFunc      LoadTemplateFunc
 	loadEmbedFunc LoadEmbedFSTemplateFunc
+	loadFSFunc    LoadFSTemplateFunc // 加载 fs.FS 中的主题，包括嵌入式文件系统
 }
 
 // NewDefaultThemeManager 创建默认主题管理器
@@ -596,6 +532,7 @@ func NewDefaultThemeManager(baseDir string, funcMap FuncMap, loadFunc LoadTempla
 		funcMap:   funcMap,
 		loadFunc:  loadFunc,
 		render:    NewRender(),
+		renders:   make(map[string]Render),
 	}
 }
 
@@ -607,12 +544,35 @@ func NewDefaultThemeManagerWithEmbedFS(embedFS *embed.FS, subDir string, funcMap
 		themes:        make(map[string]*Theme),
 		funcMap:       funcMap,
 		loadEmbedFunc: loadEmbedFunc,
+		loadFSFunc:    discovery.loadFSFunc,
 		render:        NewRender(),
+		renders:       make(map[string]Render),
+	}
+}
+
+// NewDefaultThemeManagerWithFS 创建任意文件系统的默认主题管理器，subDir 为主题根目录在 fsys 中的路径，"." 表示根目录
+func NewDefaultThemeManagerWithFS(fsys fs.FS, subDir string, funcMap FuncMap, loadFunc LoadFSTemplateFunc) *DefaultThemeManager {
+	discovery := NewThemeDiscoveryWithFS(fsys, subDir, funcMap, loadFunc)
+	return &DefaultThemeManager{
+		discovery:  discovery,
+		themes:     make(map[string]*Theme),
+		funcMap:    funcMap,
+		loadFSFunc: loadFunc,
+		render:     NewRender(),
+		renders:    make(map[string]Render),
 	}
 }
 
+// SetExtensions 设置发现和校验主题时识别的模板文件扩展名，见 ThemeDiscovery.SetExtensions
+func (tm *DefaultThemeManager) SetExtensions(exts ...string) {
+	tm.discovery.SetExtensions(exts...)
+}
+
 // DiscoverThemes 发现和加载主题
 func (tm *DefaultThemeManager) DiscoverThemes() error {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+
 	// 检测模式
 	mode, err := tm.discovery.DetectMode()
 	if err != nil {
@@ -626,6 +586,7 @@ func (tm *DefaultThemeManager) DiscoverThemes() error {
 
 	// 清空现有主题
 	tm.themes = make(map[string]*Theme)
+	tm.renders = make(map[string]Render)
 
 	switch mode {
 	case ModeLegacy:
@@ -645,16 +606,8 @@ func (tm *DefaultThemeManager) DiscoverThemes() error {
 
 // discoverLegacyTheme 发现传统模式主题
 func (tm *DefaultThemeManager) discoverLegacyTheme() error {
-	var basePath string
-	var isEmbedded bool
-
-	if tm.discovery.embedFS != nil {
-		basePath = tm.discovery.subDir
-		isEmbedded = true
-	} else {
-		basePath = tm.discovery.baseDir
-		isEmbedded = false
-	}
+	basePath := tm.discovery.root()
+	isEmbedded := tm.discovery.fsys != nil
 
 	// 验证传统结构
 	if err := tm.discovery.ValidateTheme(basePath); err != nil {
@@ -686,7 +639,7 @@ func (tm *DefaultThemeManager) discoverLegacyTheme() error {
 	tm.defaultTheme = "default"
 
 	// 加载默认主题的模板
-	if _, err := tm.LoadTheme("default"); err != nil {
+	if _, err := tm.loadTheme("default"); err != nil {
 		return &ThemeError{
 			Type:    ErrThemeLoadFailed,
 			Theme:   "default",
@@ -698,20 +651,10 @@ func (tm *DefaultThemeManager) discoverLegacyTheme() error {
 	return nil
 }
 
-// discoverMultipleThemes 发现多个主题
+// discoverMultipleThemes 发现多个主题，所有主题都在发现时加载，任一主题的模板有误时返回错误
 func (tm *DefaultThemeManager) discoverMultipleThemes() error {
-	var entries []fs.DirEntry
-	var err error
-	var basePath string
-
-	if tm.discovery.embedFS != nil {
-		basePath = tm.discovery.subDir
-		entries, err = fs.ReadDir(tm.discovery.embedFS, basePath)
-	} else {
-		basePath = tm.discovery.baseDir
-		entries, err = os.ReadDir(basePath)
-	}
-
+	basePath := tm.discovery.root()
+	entries, err := tm.discovery.readDir(basePath)
 	if err != nil {
 		return &ThemeError{
 			Type:    ErrThemeLoadFailed,
@@ -728,13 +671,7 @@ func (tm *DefaultThemeManager) discoverMultipleThemes() error {
 		}
 
 		themeName := entry.Name()
-		var themePath string
-
-		if tm.discovery.embedFS != nil {
-			themePath = filepath.Join(basePath, themeName)
-		} else {
-			themePath = filepath.Join(basePath, themeName)
-		}
+		themePath := tm.discovery.join(basePath, themeName)
 
 		// 验证主题
 		if err := tm.discovery.ValidateTheme(themePath); err != nil {
@@ -753,7 +690,7 @@ func (tm *DefaultThemeManager) discoverMultipleThemes() error {
 			Name:       themeName,
 			Path:       themePath,
 			IsDefault:  foundThemes == 0, // 第一个发现的主题作为默认主题
-			IsEmbedded: tm.discovery.embedFS != nil,
+			IsEmbedded: tm.discovery.fsys != nil,
 			Metadata:   *metadata,
 		}
 
@@ -764,15 +701,15 @@ func (tm *DefaultThemeManager) discoverMultipleThemes() error {
 		if foundThemes == 1 {
 			tm.defaultTheme = themeName
 			tm.currentTheme = themeName
+		}
 
-			// 加载第一个主题的模板
-			if _, err := tm.LoadTheme(themeName); err != nil {
-				return &ThemeError{
-					Type:    ErrThemeLoadFailed,
-					Theme:   themeName,
-					Message: "failed to load initial theme during discovery",
-					Cause:   err,
-				}
+		// 加载主题的模板，所有主题的渲染器并存以支持按请求选择主题
+		if _, err := tm.loadTheme(themeName); err != nil {
+			return &ThemeError{
+				Type:    ErrThemeLoadFailed,
+				Theme:   themeName,
+				Message: "failed to load theme during discovery",
+				Cause:   err,
 			}
 		}
 	}
@@ -790,6 +727,14 @@ func (tm *DefaultThemeManager) discoverMultipleThemes() error {
 
 // LoadTheme 加载指定主题
 func (tm *DefaultThemeManager) LoadTheme(name string) (*Theme, error) {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+
+	return tm.loadTheme(name)
+}
+
+// loadTheme 加载指定主题，调用方需持有写锁
+func (tm *DefaultThemeManager) loadTheme(name string) (*Theme, error) {
 	theme, exists := tm.themes[name]
 	if !exists {
 		return nil, &ThemeError{
@@ -814,45 +759,64 @@ func (tm *DefaultThemeManager) LoadTheme(name string) (*Theme, error) {
 
 // loadThemeTemplates 加载主题模板
 func (tm *DefaultThemeManager) loadThemeTemplates(theme *Theme) error {
-	// 释放之前的渲染器资源
-	tm.clearRender()
+	render, err := tm.buildRender(theme)
+	if err != nil {
+		return err
+	}
+
+	tm.renders[theme.Name] = render
+	// 当前主题的渲染器同步更新
+	if theme.Name == tm.currentTheme {
+		tm.render = render
+	}
 
-	// 创建新的渲染器
+	return nil
+}
+
+// buildRender 为主题创建新的渲染器，不修改管理器状态
+func (tm *DefaultThemeManager) buildRender(theme *Theme) (Render, error) {
+	var render Render
+	var err error
+
+	// 加载函数在模板有误时会panic，转换为错误以保留之前可用的渲染器
 	if theme.IsEmbedded {
-		if tm.loadEmbedFunc == nil {
-			return fmt.Errorf("embedded load function not available")
+		if tm.loadFSFunc == nil {
+			return nil, fmt.Errorf("embedded load function not available")
 		}
-		tm.render = tm.loadEmbedFunc(tm.discovery.embedFS, theme.Path, tm.funcMap)
+		render, err = recoverLoad(func() Render {
+			return tm.loadFSFunc(tm.discovery.fsys, theme.Path, tm.funcMap)
+		})
 	} else {
 		if tm.loadFunc == nil {
-			return fmt.Errorf("file system load function not available")
+			return nil, fmt.Errorf("file system load function not available")
 		}
-		tm.render = tm.loadFunc(theme.Path, tm.funcMap)
+		render, err = recoverLoad(func() Render {
+			return tm.loadFunc(theme.Path, tm.funcMap)
+		})
+	}
+	if err != nil {
+		return nil, err
 	}
 
 	// 验证渲染器是否成功创建
-	if tm.render == nil {
-		return fmt.Errorf("failed to create render for theme %s", theme.Name)
+	if render == nil {
+		return nil, fmt.Errorf("failed to create render for theme %s", theme.Name)
 	}
 
 	// 验证渲染器包含模板
-	renderMap := map[string]*template.Template(tm.render)
+	renderMap := map[string]*template.Template(render)
 	if len(renderMap) == 0 {
-		return fmt.Errorf("render contains no templates for theme %s", theme.Name)
+		return nil, fmt.Errorf("render contains no templates for theme %s", theme.Name)
 	}
 
-	return nil
-}
-
-// clearRender 清理渲染器资源
-func (tm *DefaultThemeManager) clearRender() {
-	// 创建新的空渲染器而不是清空现有的
-	// 这样可以避免影响已经分配给引擎的渲染器引用
-	tm.render = NewRender()
+	return render, nil
 }
 
 // GetRenderStats 获取渲染器统计信息
 func (tm *DefaultThemeManager) GetRenderStats() map[string]int {
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
+
 	stats := make(map[string]int)
 
 	if tm.render == nil {
@@ -895,6 +859,9 @@ func (tm *DefaultThemeManager) GetRenderStats() map[string]int {
 
 // ValidateRenderIntegrity 验证渲染器完整性
 func (tm *DefaultThemeManager) ValidateRenderIntegrity() error {
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
+
 	if tm.render == nil {
 		return fmt.Errorf("render is nil")
 	}
@@ -934,7 +901,9 @@ func (tm *DefaultThemeManager) ValidateRenderIntegrity() error {
 
 // PreloadTheme 预加载主题（不切换当前主题）
 func (tm *DefaultThemeManager) PreloadTheme(name string) error {
+	tm.mu.RLock()
 	theme, exists := tm.themes[name]
+	tm.mu.RUnlock()
 	if !exists {
 		return &ThemeError{
 			Type:    ErrThemeNotFound,
@@ -944,35 +913,12 @@ func (tm *DefaultThemeManager) PreloadTheme(name string) error {
 	}
 
 	// 创建临时渲染器进行预加载验证
-	var tempRender Render
-
-	if theme.IsEmbedded {
-		if tm.loadEmbedFunc == nil {
-			return fmt.Errorf("embedded load function not available")
-		}
-		tempRender = tm.loadEmbedFunc(tm.discovery.embedFS, theme.Path, tm.funcMap)
-	} else {
-		if tm.loadFunc == nil {
-			return fmt.Errorf("file system load function not available")
-		}
-		tempRender = tm.loadFunc(theme.Path, tm.funcMap)
-	}
-
-	// 验证预加载的渲染器
-	if tempRender == nil {
+	if _, err := tm.buildRender(theme); err != nil {
 		return &ThemeError{
 			Type:    ErrThemeLoadFailed,
 			Theme:   name,
 			Message: "failed to preload theme",
-		}
-	}
-
-	renderMap := map[string]*template.Template(tempRender)
-	if len(renderMap) == 0 {
-		return &ThemeError{
-			Type:    ErrThemeLoadFailed,
-			Theme:   name,
-			Message: "preloaded theme contains no templates",
+			Cause:   err,
 		}
 	}
 
@@ -982,6 +928,9 @@ func (tm *DefaultThemeManager) PreloadTheme(name string) error {
 
 // GetTemplateNames 获取当前渲染器中的所有模板名称
 func (tm *DefaultThemeManager) GetTemplateNames() []string {
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
+
 	if tm.render == nil {
 		return []string{}
 	}
@@ -998,6 +947,9 @@ func (tm *DefaultThemeManager) GetTemplateNames() []string {
 
 // HasTemplate 检查是否存在指定名称的模板
 func (tm *DefaultThemeManager) HasTemplate(templateName string) bool {
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
+
 	if tm.render == nil {
 		return false
 	}
@@ -1009,6 +961,9 @@ func (tm *DefaultThemeManager) HasTemplate(templateName string) bool {
 
 // GetMemoryUsage 获取内存使用估算（简单实现）
 func (tm *DefaultThemeManager) GetMemoryUsage() map[string]any {
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
+
 	usage := make(map[string]any)
 
 	usage["themes_count"] = len(tm.themes)
@@ -1028,6 +983,9 @@ func (tm *DefaultThemeManager) GetMemoryUsage() map[string]any {
 
 // GetAvailableThemes 获取所有可用主题
 func (tm *DefaultThemeManager) GetAvailableThemes() []string {
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
+
 	themes := make([]string, 0, len(tm.themes))
 	for name := range tm.themes {
 		themes = append(themes, name)
@@ -1037,17 +995,43 @@ func (tm *DefaultThemeManager) GetAvailableThemes() []string {
 
 // GetCurrentTheme 获取当前主题
 func (tm *DefaultThemeManager) GetCurrentTheme() string {
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
+
 	return tm.currentTheme
 }
 
+// GetTheme 获取指定主题的信息，不会重新加载模板
+func (tm *DefaultThemeManager) GetTheme(name string) (*Theme, error) {
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
+
+	theme, exists := tm.themes[name]
+	if !exists {
+		return nil, &ThemeError{
+			Type:    ErrThemeNotFound,
+			Theme:   name,
+			Message: "theme not found",
+		}
+	}
+
+	return theme, nil
+}
+
 // ThemeExists 检查主题是否存在
 func (tm *DefaultThemeManager) ThemeExists(name string) bool {
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
+
 	_, exists := tm.themes[name]
 	return exists
 }
 
 // GetThemeMetadata 获取主题元数据
 func (tm *DefaultThemeManager) GetThemeMetadata(name string) (*ThemeMetadata, error) {
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
+
 	theme, exists := tm.themes[name]
 	if !exists {
 		return nil, &ThemeError{
@@ -1062,8 +1046,11 @@ func (tm *DefaultThemeManager) GetThemeMetadata(name string) (*ThemeMetadata, er
 
 // SwitchTheme 切换主题
 func (tm *DefaultThemeManager) SwitchTheme(name string) error {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+
 	// 检查主题是否存在
-	if !tm.ThemeExists(name) {
+	if _, exists := tm.themes[name]; !exists {
 		return &ThemeError{
 			Type:    ErrThemeNotFound,
 			Theme:   name,
@@ -1076,16 +1063,9 @@ func (tm *DefaultThemeManager) SwitchTheme(name string) error {
 		return nil
 	}
 
-	// 保存当前状态以便回滚
-	previousTheme := tm.currentTheme
-	previousRender := tm.render
-
-	// 尝试加载新主题
-	_, err := tm.LoadTheme(name)
+	// 优先使用已加载的渲染器，未加载时再加载新主题
+	render, err := tm.themeRender(name)
 	if err != nil {
-		// 确保状态没有被破坏
-		tm.currentTheme = previousTheme
-		tm.render = previousRender
 		return &ThemeError{
 			Type:    ErrThemeSwitchFailed,
 			Theme:   name,
@@ -1094,23 +1074,8 @@ func (tm *DefaultThemeManager) SwitchTheme(name string) error {
 		}
 	}
 
-	// 验证新渲染器是否有效
-	if tm.render == nil {
-		// 回滚到之前的状态
-		tm.currentTheme = previousTheme
-		tm.render = previousRender
-		return &ThemeError{
-			Type:    ErrThemeSwitchFailed,
-			Theme:   name,
-			Message: "theme switch failed: render is nil after loading",
-		}
-	}
-
 	// 验证渲染器是否包含必要的模板
-	if err := tm.validateRenderTemplates(); err != nil {
-		// 回滚到之前的状态
-		tm.currentTheme = previousTheme
-		tm.render = previousRender
+	if err := tm.validateRenderTemplates(render); err != nil {
 		return &ThemeError{
 			Type:    ErrThemeSwitchFailed,
 			Theme:   name,
@@ -1119,51 +1084,39 @@ func (tm *DefaultThemeManager) SwitchTheme(name string) error {
 		}
 	}
 
-	// 成功切换，更新当前主题
+	// 成功切换，更新当前主题和渲染器
 	tm.currentTheme = name
-
-	// 更新主题的默认状态（只有当前主题被标记为活跃）
-	for themeName, t := range tm.themes {
-		// 不改变IsDefault状态，只是切换当前活跃主题
-		_ = themeName
-		_ = t
-	}
+	tm.render = render
 
 	return nil
 }
 
 // validateRenderTemplates 验证渲染器中的模板
-func (tm *DefaultThemeManager) validateRenderTemplates() error {
-	if tm.render == nil {
+func (tm *DefaultThemeManager) validateRenderTemplates(render Render) error {
+	if render == nil {
 		return fmt.Errorf("render is nil")
 	}
 
 	// 检查渲染器是否为空
-	renderMap := map[string]*template.Template(tm.render)
+	renderMap := map[string]*template.Template(render)
 	if len(renderMap) == 0 {
 		return fmt.Errorf("render contains no templates")
 	}
 
 	// 基本验证：确保至少有一些模板被加载
-	hasTemplates := false
-	for name, tmpl := range renderMap {
+	for _, tmpl := range renderMap {
 		if tmpl != nil {
-			hasTemplates = true
-			break
+			return nil
 		}
-		_ = name // 避免未使用变量警告
 	}
 
-	if !hasTemplates {
-		return fmt.Errorf("render contains no valid templates")
-	}
-
-	return nil
+	return fmt.Errorf("render contains no valid templates")
 }
 
 // SwitchToDefaultTheme 切换到默认主题
 func (tm *DefaultThemeManager) SwitchToDefaultTheme() error {
-	if tm.defaultTheme == "" {
+	defaultTheme := tm.GetDefaultTheme()
+	if defaultTheme == "" {
 		return &ThemeError{
 			Type:    ErrThemeNotFound,
 			Theme:   "",
@@ -1171,18 +1124,18 @@ func (tm *DefaultThemeManager) SwitchToDefaultTheme() error {
 		}
 	}
 
-	return tm.SwitchTheme(tm.defaultTheme)
+	return tm.SwitchTheme(defaultTheme)
 }
 
 // SafeSwitchTheme 安全切换主题（如果失败则保持当前主题）
 func (tm *DefaultThemeManager) SafeSwitchTheme(name string) error {
 	// 记录切换尝试
-	originalTheme := tm.currentTheme
+	originalTheme := tm.GetCurrentTheme()
 
 	err := tm.SwitchTheme(name)
 	if err != nil {
 		// 确保我们仍然在原始主题上
-		if tm.currentTheme != originalTheme {
+		if tm.GetCurrentTheme() != originalTheme {
 			// 尝试恢复到原始主题
 			if restoreErr := tm.SwitchTheme(originalTheme); restoreErr != nil {
 				// 如果无法恢复，这是一个严重错误
@@ -1202,11 +1155,44 @@ func (tm *DefaultThemeManager) SafeSwitchTheme(name string) error {
 
 // GetRender 获取渲染器
 func (tm *DefaultThemeManager) GetRender() Render {
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
+
 	return tm.render
 }
 
+// GetThemeRender 获取指定主题的渲染器，不切换当前主题
+func (tm *DefaultThemeManager) GetThemeRender(name string) (Render, error) {
+	tm.mu.RLock()
+	render, ok := tm.renders[name]
+	tm.mu.RUnlock()
+	if ok && render != nil {
+		return render, nil
+	}
+
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+
+	return tm.themeRender(name)
+}
+
+// themeRender 获取指定主题的渲染器，尚未加载时按需加载，调用方需持有写锁
+func (tm *DefaultThemeManager) themeRender(name string) (Render, error) {
+	if render, ok := tm.renders[name]; ok && render != nil {
+		return render, nil
+	}
+
+	if _, err := tm.loadTheme(name); err != nil {
+		return nil, err
+	}
+	return tm.renders[name], nil
+}
+
 // ReloadCurrentTheme 重新加载当前主题
 func (tm *DefaultThemeManager) ReloadCurrentTheme() error {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+
 	if tm.currentTheme == "" {
 		return &ThemeError{
 			Type:    ErrThemeLoadFailed,
@@ -1215,13 +1201,16 @@ func (tm *DefaultThemeManager) ReloadCurrentTheme() error {
 		}
 	}
 
-	_, err := tm.LoadTheme(tm.currentTheme)
+	_, err := tm.loadTheme(tm.currentTheme)
 	return err
 }
 
 // SetDefaultTheme 设置默认主题
 func (tm *DefaultThemeManager) SetDefaultTheme(name string) error {
-	if !tm.ThemeExists(name) {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+
+	if _, exists := tm.themes[name]; !exists {
 		return &ThemeError{
 			Type:    ErrThemeNotFound,
 			Theme:   name,
@@ -1241,23 +1230,15 @@ func (tm *DefaultThemeManager) SetDefaultTheme(name string) error {
 
 // GetDefaultTheme 获取默认主题名称
 func (tm *DefaultThemeManager) GetDefaultTheme() string {
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
+
 	return tm.defaultTheme
 }
 
 // LoadThemeMetadata 加载主题元数据
 func (td *ThemeDiscovery) LoadThemeMetadata(themePath string) (*ThemeMetadata, error) {
-	var metadataPath string
-	var data []byte
-	var err error
-
-	if td.embedFS != nil {
-		metadataPath = filepath.Join(themePath, "theme.json")
-		data, err = fs.ReadFile(td.embedFS, metadataPath)
-	} else {
-		metadataPath = filepath.Join(themePath, "theme.json")
-		data, err = os.ReadFile(metadataPath)
-	}
-
+	data, err := td.readFile(td.join(themePath, "theme.json"))
 	if err != nil {
 		// 如果没有theme.json文件，返回默认元数据
 		return &ThemeMetadata{
@@ -1684,7 +1684,10 @@ func TestProperty_BackwardCompatibilityPreservation(t *testing.T) {
 		}
 
 		// 初始化引擎
-		legacyEngine.Init()
+		if err := legacyEngine.Init(); err != nil {
+			t.Logf("Failed to initialize engine: %v", err)
+			return false
+		}
 
 		// 验证引擎基本状态
 		if legacyEngine.HTMLRender == nil {
@@ -1719,7 +1722,10 @@ func TestProperty_BackwardCompatibilityPreservation(t *testing.T) {
 			return false
 		}
 
-		modernEngine.Init()
+		if err := modernEngine.Init(); err != nil {
+			t.Logf("Failed to initialize engine: %v", err)
+			return false
+		}
 
 		// 验证现代引擎也能正常工作
 		if modernEngine.HTMLRender == nil {
@@ -1783,7 +1789,10 @@ func TestProperty_BackwardCompatibilityPreservation(t *testing.T) {
 			return false
 		}
 
-		legacyEngineWithOpts.Init()
+		if err := legacyEngineWithOpts.Init(); err != nil {
+			t.Logf("Failed to initialize engine: %v", err)
+			return false
+		}
 
 		// 验证选项被正确应用
 		if legacyEngineWithOpts.opts.Layout != "custom.tmpl" {
@@ -1818,7 +1827,10 @@ func TestProperty_BackwardCompatibilityPreservation(t *testing.T) {
 			return false
 		}
 
-		watchEngine.Init()
+		if err := watchEngine.Init(); err != nil {
+			t.Logf("Failed to initialize engine: %v", err)
+			return false
+		}
 
 		// 启动文件监听应该成功
 		err = watchEngine.Watching()
@@ -1917,7 +1929,9 @@ func TestBackwardCompatibilityExamples(t *testing.T) {
 			}
 
 			// 初始化引擎
-			engine.Init()
+			if err := engine.Init(); err != nil {
+				t.Fatalf("Failed to initialize engine: %v", err)
+			}
 
 			// 验证基本功能
 			if engine.HTMLRender == nil {
@@ -1983,7 +1997,10 @@ func TestProperty_FileWatchingIntegration(t *testing.T) {
 			return false
 		}
 
-		engine.Init()
+		if err := engine.Init(); err != nil {
+			t.Logf("Failed to initialize engine: %v", err)
+			return false
+		}
 
 		// 验证引擎初始化成功
 		if engine.HTMLRender == nil {
@@ -2025,7 +2042,7 @@ func TestProperty_FileWatchingIntegration(t *testing.T) {
 		// 测试文件是否属于当前主题的检查
 		// 创建一个属于当前主题的文件路径
 		themeFilePath := filepath.Join(watchDir, "pages", "test.tmpl")
-		if !engine.isFileInCurrentTheme(themeFilePath) {
+		if !inCurrentTheme(engine, themeFilePath) {
 			t.Logf("File in current theme should be recognized as such: %s", themeFilePath)
 			return false
 		}
@@ -2041,7 +2058,7 @@ func TestProperty_FileWatchingIntegration(t *testing.T) {
 
 		if otherThemeDir != "" {
 			otherThemeFilePath := filepath.Join(otherThemeDir, "pages", "other.tmpl")
-			if engine.isFileInCurrentTheme(otherThemeFilePath) {
+			if inCurrentTheme(engine, otherThemeFilePath) {
 				t.Logf("File in other theme should not be recognized as current theme file: %s", otherThemeFilePath)
 				return false
 			}
@@ -2136,14 +2153,14 @@ func TestProperty_FileWatchingIntegration(t *testing.T) {
 
 				// 验证文件归属检查已更新
 				newThemeFilePath := filepath.Join(newWatchDir, "pages", "new.tmpl")
-				if !engine.isFileInCurrentTheme(newThemeFilePath) {
+				if !inCurrentTheme(engine, newThemeFilePath) {
 					t.Logf("File in new current theme should be recognized: %s", newThemeFilePath)
 					return false
 				}
 
 				// 验证旧主题文件不再被认为是当前主题的文件
 				oldThemeFilePath := filepath.Join(oldWatchDir, "pages", "old.tmpl")
-				if engine.isFileInCurrentTheme(oldThemeFilePath) {
+				if inCurrentTheme(engine, oldThemeFilePath) {
 					t.Logf("File in old theme should not be recognized as current: %s", oldThemeFilePath)
 					return false
 				}
@@ -2226,7 +2243,7 @@ func TestProperty_FileWatchingIntegration(t *testing.T) {
 // TestProperty_FileWatchingThemeIsolation 测试文件监听主题隔离
 // Feature: multi-theme-support, Property 4: File Watching Integration
 func TestProperty_FileWatchingThemeIsolation(t *testing.T) {
-	// 属性：文件监听应该只对当前活跃主题的文件变化做出响应，忽略其他主题的文件变化
+	// 属性：文件变化只重新加载其所属的主题，忽略主题目录之外的文件变化
 
 	property := func() bool {
 		// 创建临时目录
@@ -2256,7 +2273,10 @@ func TestProperty_FileWatchingThemeIsolation(t *testing.T) {
 			return false
 		}
 
-		engine.Init()
+		if err := engine.Init(); err != nil {
+			t.Logf("Failed to initialize engine: %v", err)
+			return false
+		}
 
 		currentTheme := engine.GetCurrentTheme()
 		if currentTheme == "" {
@@ -2291,13 +2311,13 @@ func TestProperty_FileWatchingThemeIsolation(t *testing.T) {
 				return false
 			}
 
-			if !engine.isFileInCurrentTheme(filePath) {
+			if !inCurrentTheme(engine, filePath) {
 				t.Logf("File should be recognized as in current theme: %s", filePath)
 				return false
 			}
 		}
 
-		// 测试其他主题中的文件不应该触发重载
+		// 测试其他主题中的文件触发其所属主题的重载
 		for _, themeName := range themes {
 			if themeName == currentTheme {
 				continue // 跳过当前主题
@@ -2312,12 +2332,17 @@ func TestProperty_FileWatchingThemeIsolation(t *testing.T) {
 			}
 
 			for _, filePath := range otherThemeFiles {
-				if engine.shouldReloadForFile(filePath) {
-					t.Logf("File in other theme should not trigger reload: %s", filePath)
+				if !engine.shouldReloadForFile(filePath) {
+					t.Logf("File in other theme should trigger reload: %s", filePath)
 					return false
 				}
 
-				if engine.isFileInCurrentTheme(filePath) {
+				if owner, _ := engine.themeForFile(filePath); owner != themeName {
+					t.Logf("File should belong to theme %s, got %s: %s", themeName, owner, filePath)
+					return false
+				}
+
+				if inCurrentTheme(engine, filePath) {
 					t.Logf("File should not be recognized as in current theme: %s", filePath)
 					return false
 				}
@@ -2366,15 +2391,20 @@ func TestProperty_FileWatchingThemeIsolation(t *testing.T) {
 					}
 				}
 
-				// 验证旧主题文件现在不应该触发重载
+				// 验证旧主题文件不再属于当前主题，但仍然触发旧主题的重载
 				oldThemeFiles := []string{
 					filepath.Join(oldThemeDir, "pages", "old.tmpl"),
 					filepath.Join(oldThemeDir, "layouts", "old.tmpl"),
 				}
 
 				for _, filePath := range oldThemeFiles {
-					if engine.shouldReloadForFile(filePath) {
-						t.Logf("File in old theme should not trigger reload after switch: %s", filePath)
+					if inCurrentTheme(engine, filePath) {
+						t.Logf("File in old theme should not be in current theme after switch: %s", filePath)
+						return false
+					}
+
+					if owner, ok := engine.themeForFile(filePath); !ok || owner != currentTheme {
+						t.Logf("File in old theme should still belong to %s after switch: %s", currentTheme, filePath)
 						return false
 					}
 				}
@@ -2407,7 +2437,7 @@ func TestProperty_FileWatchingThemeIsolation(t *testing.T) {
 				return false
 			}
 
-			if engine.isFileInCurrentTheme(filePath) {
+			if inCurrentTheme(engine, filePath) {
 				t.Logf("File outside theme directories should not be in current theme: %s", filePath)
 				return false
 			}
@@ -2450,7 +2480,9 @@ func TestFileWatchingIntegrationExamples(t *testing.T) {
 			t.Fatalf("Failed to create legacy engine: %v", err)
 		}
 
-		engine.Init()
+		if err := engine.Init(); err != nil {
+			t.Fatalf("Failed to initialize engine: %v", err)
+		}
 
 		// 验证监听目录
 		watchDir := engine.getWatchDirectory()
@@ -2465,7 +2497,7 @@ func TestFileWatchingIntegrationExamples(t *testing.T) {
 
 		// 验证文件归属检查
 		legacyFile := filepath.Join(legacyDir, "pages", "test.tmpl")
-		if !engine.isFileInCurrentTheme(legacyFile) {
+		if !inCurrentTheme(engine, legacyFile) {
 			t.Errorf("Legacy file should be in current theme")
 		}
 
@@ -2497,7 +2529,9 @@ func TestFileWatchingIntegrationExamples(t *testing.T) {
 			t.Fatalf("Failed to create multi-theme engine: %v", err)
 		}
 
-		engine.Init()
+		if err := engine.Init(); err != nil {
+			t.Fatalf("Failed to initialize engine: %v", err)
+		}
 
 		currentTheme := engine.GetCurrentTheme()
 		if currentTheme == "" {
@@ -2512,7 +2546,7 @@ func TestFileWatchingIntegrationExamples(t *testing.T) {
 
 		// 验证当前主题文件被正确识别
 		currentThemeFile := filepath.Join(watchDir, "pages", "current.tmpl")
-		if !engine.isFileInCurrentTheme(currentThemeFile) {
+		if !inCurrentTheme(engine, currentThemeFile) {
 			t.Errorf("Current theme file should be recognized")
 		}
 
@@ -2520,20 +2554,29 @@ func TestFileWatchingIntegrationExamples(t *testing.T) {
 			t.Errorf("Current theme template should trigger reload")
 		}
 
-		// 验证其他主题文件被正确排除
+		// 验证其他主题文件不属于当前主题，但会触发所属主题的重载
 		for _, themeName := range themes {
 			if themeName != currentTheme {
 				otherThemeFile := filepath.Join(multiDir, themeName, "pages", "other.tmpl")
-				if engine.isFileInCurrentTheme(otherThemeFile) {
+				if inCurrentTheme(engine, otherThemeFile) {
 					t.Errorf("Other theme file should not be in current theme")
 				}
 
-				if engine.shouldReloadForFile(otherThemeFile) {
-					t.Errorf("Other theme file should not trigger reload")
+				if !engine.shouldReloadForFile(otherThemeFile) {
+					t.Errorf("Other theme file should trigger reload of its theme")
+				}
+
+				if owner, _ := engine.themeForFile(otherThemeFile); owner != themeName {
+					t.Errorf("Other theme file should belong to %s, got %s", themeName, owner)
 				}
 			}
 		}
 
+		// 所有主题的目录都被监听
+		if len(engine.GetWatchedDirectories()) != len(themes) {
+			t.Errorf("Expected %d watched directories, got %v", len(themes), engine.GetWatchedDirectories())
+		}
+
 		// 测试主题切换
 		for _, themeName := range themes {
 			if themeName != currentTheme {
@@ -3051,7 +3094,9 @@ func TestEngineThemeAPIMethods(t *testing.T) {
 			t.Fatalf("Failed to create engine: %v", err)
 		}
 
-		engine.Init()
+		if err := engine.Init(); err != nil {
+			t.Fatalf("Failed to initialize engine: %v", err)
+		}
 
 		// 测试GetAvailableThemes
 		themes := engine.GetAvailableThemes()
@@ -3126,7 +3171,9 @@ func TestEngineThemeAPIMethods(t *testing.T) {
 			t.Fatalf("Failed to create multi-theme engine: %v", err)
 		}
 
-		engine.Init()
+		if err := engine.Init(); err != nil {
+			t.Fatalf("Failed to initialize engine: %v", err)
+		}
 
 		// 关闭文件监听器以避免测试中的竞态条件
 		if engine.watcher != nil {
@@ -3201,3 +3248,9 @@ func TestEngineThemeAPIMethods(t *testing.T) {
 		}
 	})
 }
+
+// inCurrentTheme 检查文件是否属于当前主题
+func inCurrentTheme(en *Engine, filePath string) bool {
+	theme, ok := en.themeForFile(filePath)
+	return ok && theme == en.currentThemeName()
+}
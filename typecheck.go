@@ -0,0 +1,438 @@
+package template
+
+import (
+	"context"
+	"fmt"
+	"html/template"
+	"path"
+	"reflect"
+	"strconv"
+	"strings"
+	"text/template/parse"
+)
+
+// TypeCheckError 模板引用了视图模型中不存在的字段
+type TypeCheckError struct {
+	Theme    string // 主题名称，传统模式下为空
+	Template string // 模板集合名称，如 layout.tmpl:pages/posts/detail
+	File     string // 出错的模板文件
+	Line     int    // 出错的行号
+	Field    string // 出错的字段链，如 .post.Titel
+	Type     string // 找不到字段的类型
+}
+
+// Error 实现error接口
+func (e *TypeCheckError) Error() string {
+	name := e.Template
+	if e.Theme != "" {
+		name = e.Theme + "/" + name
+	}
+	return fmt.Sprintf("%s:%d: %s: can't evaluate field %s in type %s", e.File, e.Line, name, e.Field, e.Type)
+}
+
+// TypeChecker 根据为页面注册的视图模型，静态检查模板中的字段引用。
+// 检查从页面的入口模板开始，沿 {{template}} 调用传递 dot 的类型；
+// 类型无法静态确定时（函数返回值、interface{}、map 的值等）不再继续检查。
+type TypeChecker struct {
+	opts   Options
+	models []typeModel
+}
+
+// typeModel 为页面注册的视图模型
+type typeModel struct {
+	kind string
+	name string
+	root *typeInfo
+}
+
+// typeInfo 检查过程中值的类型，为 nil 时表示类型未知
+type typeInfo struct {
+	typ  reflect.Type            // 值的类型
+	name string                  // 根视图的类型名称，用于错误信息
+	keys map[string]reflect.Type // 根视图的键，不为 nil 时表示引擎生成的模板数据
+}
+
+// NewTypeChecker 创建类型检查器，opts 中的 ReservedKeys 需与引擎一致
+func NewTypeChecker(opts ...Option) *TypeChecker {
+	return &TypeChecker{opts: newOptions(opts...)}
+}
+
+// Register 为页面注册视图模型，model 为传给 RenderPageData 等方法的数据（结构体或其指针）。
+// kind 为 KindPage、KindSingle 或 KindError，name 为渲染时使用的名称，如 posts/detail。
+func (c *TypeChecker) Register(kind, name string, model any) *TypeChecker {
+	typ := reflect.TypeOf(model)
+	keys := map[string]reflect.Type{}
+	base := typ
+	for base != nil && base.Kind() == reflect.Pointer {
+		base = base.Elem()
+	}
+	if base == nil || base.Kind() != reflect.Struct {
+		// 不是结构体时按普通类型检查字段
+		c.models = append(c.models, typeModel{kind: kind, name: name, root: newTypeInfo(typ)})
+		return c
+	}
+	for _, field := range viewFields(base) {
+		keys[field.Name] = field.Type
+	}
+	c.add(kind, name, base.String(), keys)
+	return c
+}
+
+// RegisterView 为使用 RenderPageT 等泛型方法渲染的页面注册视图模型，模板中通过 .Data 访问
+func (c *TypeChecker) RegisterView(kind, name string, model any) *TypeChecker {
+	typ := reflect.TypeOf(model)
+	c.add(kind, name, "view of "+fmt.Sprint(typ), map[string]reflect.Type{viewModelKey: typ})
+	return c
+}
+
+// add 添加根视图，写入保留键，错误页面还写入引擎补充的错误数据
+func (c *TypeChecker) add(kind, name, typeName string, keys map[string]reflect.Type) {
+	reserved := map[string]reflect.Type{
+		c.opts.ConstantKey: reflect.TypeOf(c.opts.GlobalConstant),
+		c.opts.VariableKey: reflect.TypeOf(c.opts.GlobalVariable),
+		c.opts.ContextKey:  reflect.TypeOf((*context.Context)(nil)).Elem(),
+	}
+	for key, typ := range reserved {
+		if key != "" {
+			keys[key] = typ
+		}
+	}
+	if kind == KindError {
+		// 错误页面数据中补充的状态码、状态说明和请求ID，调用方已提供的值不会被覆盖，见 addErrorData
+		errorKeys := map[string]reflect.Type{
+			"status":    reflect.TypeOf(0),
+			"message":   reflect.TypeOf(""),
+			"requestID": reflect.TypeOf(""),
+		}
+		for key, typ := range errorKeys {
+			if _, ok := keys[key]; !ok {
+				keys[key] = typ
+			}
+		}
+	}
+	c.models = append(c.models, typeModel{
+		kind: kind,
+		name: name,
+		root: &typeInfo{name: typeName, keys: keys},
+	})
+}
+
+// Check 检查渲染器中已注册页面的模板，返回全部 *TypeCheckError（多个时为 LoadErrors）。
+// 应在启动时或测试中调用，不要与首次渲染并发执行。
+func (c *TypeChecker) Check(render Render) error {
+	var errs LoadErrors
+	c.check("", render, &errs)
+	return errs.err()
+}
+
+// check 检查一个主题的渲染器
+func (c *TypeChecker) check(theme string, render Render, errs *LoadErrors) {
+	for key, tmpl := range render {
+		for _, model := range c.models {
+			if !matchTemplateKey(key, model.kind, model.name) {
+				continue
+			}
+			w := &typeWalker{
+				theme:   theme,
+				key:     key,
+				set:     tmpl,
+				errs:    errs,
+				visited: map[visitKey]bool{},
+			}
+			w.template(tmpl.Name(), model.root)
+		}
+	}
+}
+
+// CheckTypes 使用类型检查器检查所有已加载主题的模板，见 TypeChecker.Check
+func (en *Engine) CheckTypes(c *TypeChecker) error {
+	var errs LoadErrors
+	set := en.loadRenders()
+	if len(set.themes) == 0 {
+		c.check(set.theme, set.current, &errs)
+		return errs.err()
+	}
+	for theme, render := range set.themes {
+		c.check(theme, render, &errs)
+	}
+	return errs.err()
+}
+
+// matchTemplateKey 判断渲染器中的模板名称是否为指定类型和名称的页面
+func matchTemplateKey(key, kind, name string) bool {
+	dirs := map[string]string{KindPage: "pages", KindSingle: "singles", KindError: "error"}
+	dir, ok := dirs[kind]
+	if !ok {
+		return false
+	}
+	if i := strings.Index(key, ":"); i >= 0 {
+		// 使用布局的模板：layout.tmpl:pages/posts/detail
+		key = key[i+1:]
+	} else {
+		// 传统单页和错误页面：singles/login.tmpl
+		key = strings.TrimSuffix(key, path.Ext(key))
+	}
+	return key == dir+"/"+name
+}
+
+// newTypeInfo 创建类型信息，类型为空时返回 nil（未知）
+func newTypeInfo(typ reflect.Type) *typeInfo {
+	if typ == nil {
+		return nil
+	}
+	return &typeInfo{typ: typ}
+}
+
+// String 返回用于错误信息的类型名称
+func (t *typeInfo) String() string {
+	if t.keys != nil {
+		return t.name
+	}
+	return t.typ.String()
+}
+
+// visitKey 已检查过的模板和 dot 类型，避免递归模板无限展开
+type visitKey struct {
+	name string
+	typ  reflect.Type
+	view *typeInfo
+}
+
+// typeWalker 遍历一个模板集合的解析树
+type typeWalker struct {
+	theme   string
+	key     string
+	set     *template.Template
+	errs    *LoadErrors
+	visited map[visitKey]bool
+}
+
+// template 以指定的 dot 类型检查模板
+func (w *typeWalker) template(name string, dot *typeInfo) {
+	tmpl := w.set.Lookup(name)
+	if tmpl == nil || tmpl.Tree == nil || tmpl.Tree.Root == nil {
+		return
+	}
+	key := visitKey{name: name}
+	if dot != nil {
+		key.typ = dot.typ
+		if dot.keys != nil {
+			key.view = dot
+		}
+	}
+	if w.visited[key] {
+		return
+	}
+	w.visited[key] = true
+	w.list(tmpl.Tree, tmpl.Tree.Root, dot, map[string]*typeInfo{"$": dot})
+}
+
+// list 检查节点列表，vars 为当前作用域的变量
+func (w *typeWalker) list(tree *parse.Tree, list *parse.ListNode, dot *typeInfo, vars map[string]*typeInfo) {
+	if list == nil {
+		return
+	}
+	for _, node := range list.Nodes {
+		switch n := node.(type) {
+		case *parse.ActionNode:
+			w.pipe(tree, n.Pipe, dot, vars)
+		case *parse.IfNode:
+			scope := copyVars(vars)
+			w.pipe(tree, n.Pipe, dot, scope)
+			w.list(tree, n.List, dot, scope)
+			w.list(tree, n.ElseList, dot, copyVars(vars))
+		case *parse.WithNode:
+			scope := copyVars(vars)
+			value := w.pipe(tree, n.Pipe, dot, scope)
+			w.list(tree, n.List, value, scope)
+			w.list(tree, n.ElseList, dot, copyVars(vars))
+		case *parse.RangeNode:
+			scope := copyVars(vars)
+			key, elem := rangeTypes(w.pipeValue(tree, n.Pipe, dot, scope))
+			switch len(n.Pipe.Decl) {
+			case 1:
+				scope[n.Pipe.Decl[0].Ident[0]] = elem
+			case 2:
+				scope[n.Pipe.Decl[0].Ident[0]] = key
+				scope[n.Pipe.Decl[1].Ident[0]] = elem
+			}
+			w.list(tree, n.List, elem, scope)
+			w.list(tree, n.ElseList, dot, copyVars(vars))
+		case *parse.TemplateNode:
+			var arg *typeInfo
+			if n.Pipe != nil {
+				arg = w.pipe(tree, n.Pipe, dot, copyVars(vars))
+			}
+			w.template(n.Name, arg)
+		case *parse.ListNode:
+			w.list(tree, n, dot, vars)
+		}
+	}
+}
+
+// pipe 检查管道并为声明的变量赋予结果类型
+func (w *typeWalker) pipe(tree *parse.Tree, pipe *parse.PipeNode, dot *typeInfo, vars map[string]*typeInfo) *typeInfo {
+	value := w.pipeValue(tree, pipe, dot, vars)
+	if pipe != nil {
+		for _, v := range pipe.Decl {
+			vars[v.Ident[0]] = value
+		}
+	}
+	return value
+}
+
+// pipeValue 检查管道中的每个命令，返回最后一个命令的结果类型
+func (w *typeWalker) pipeValue(tree *parse.Tree, pipe *parse.PipeNode, dot *typeInfo, vars map[string]*typeInfo) *typeInfo {
+	if pipe == nil {
+		return nil
+	}
+	var value *typeInfo
+	for _, cmd := range pipe.Cmds {
+		value = w.command(tree, cmd, dot, vars)
+	}
+	return value
+}
+
+// command 检查命令的参数，返回命令的结果类型
+func (w *typeWalker) command(tree *parse.Tree, cmd *parse.CommandNode, dot *typeInfo, vars map[string]*typeInfo) *typeInfo {
+	if len(cmd.Args) == 0 {
+		return nil
+	}
+	for _, arg := range cmd.Args[1:] {
+		w.operand(tree, arg, dot, vars)
+	}
+	return w.operand(tree, cmd.Args[0], dot, vars)
+}
+
+// operand 检查操作数，返回其类型
+func (w *typeWalker) operand(tree *parse.Tree, node parse.Node, dot *typeInfo, vars map[string]*typeInfo) *typeInfo {
+	switch n := node.(type) {
+	case *parse.FieldNode:
+		return w.fields(tree, n, dot, "", n.Ident)
+	case *parse.ChainNode:
+		return w.fields(tree, n, w.operand(tree, n.Node, dot, vars), "", n.Field)
+	case *parse.VariableNode:
+		return w.fields(tree, n, vars[n.Ident[0]], n.Ident[0], n.Ident[1:])
+	case *parse.DotNode:
+		return dot
+	case *parse.PipeNode:
+		return w.pipeValue(tree, n, dot, copyVars(vars))
+	default:
+		// 函数调用和字面量不检查返回类型
+		return nil
+	}
+}
+
+// fields 沿字段链解析类型，找不到字段时记录错误
+func (w *typeWalker) fields(tree *parse.Tree, node parse.Node, value *typeInfo, prefix string, idents []string) *typeInfo {
+	for i, name := range idents {
+		if value == nil {
+			return nil
+		}
+		next, ok := resolveField(value, name)
+		if !ok {
+			w.report(tree, node, prefix+"."+strings.Join(idents[:i+1], "."), value)
+			return nil
+		}
+		value = next
+	}
+	return value
+}
+
+// report 记录字段错误
+func (w *typeWalker) report(tree *parse.Tree, node parse.Node, field string, value *typeInfo) {
+	location, _ := tree.ErrorContext(node)
+	file, line := tree.ParseName, 0
+	// location 的格式为 name:line:col
+	if parts := strings.Split(location, ":"); len(parts) >= 3 {
+		file = strings.Join(parts[:len(parts)-2], ":")
+		line, _ = strconv.Atoi(parts[len(parts)-2])
+	}
+	w.errs.add(&TypeCheckError{
+		Theme:    w.theme,
+		Template: w.key,
+		File:     file,
+		Line:     line,
+		Field:    field,
+		Type:     value.String(),
+	})
+}
+
+// resolveField 解析字段或方法的类型，ok 为 false 时表示类型中确定不存在该字段
+func resolveField(value *typeInfo, name string) (*typeInfo, bool) {
+	if value.keys != nil {
+		typ, ok := value.keys[name]
+		return newTypeInfo(typ), ok
+	}
+
+	typ := value.typ
+	if method, ok := findMethod(typ, name); ok {
+		if method.Type.NumOut() == 0 {
+			return nil, true
+		}
+		return newTypeInfo(method.Type.Out(0)), true
+	}
+
+	for typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+	switch typ.Kind() {
+	case reflect.Interface:
+		// 实际类型在运行时才能确定
+		return nil, true
+	case reflect.Struct:
+		field, ok := typ.FieldByName(name)
+		if !ok || !field.IsExported() {
+			return nil, false
+		}
+		return newTypeInfo(field.Type), true
+	case reflect.Map:
+		if typ.Key().Kind() != reflect.String {
+			return nil, true
+		}
+		return newTypeInfo(typ.Elem()), true
+	default:
+		return nil, false
+	}
+}
+
+// findMethod 查找类型（包括其指针类型）的导出方法
+func findMethod(typ reflect.Type, name string) (reflect.Method, bool) {
+	if method, ok := typ.MethodByName(name); ok {
+		return method, true
+	}
+	if typ.Kind() != reflect.Pointer && typ.Kind() != reflect.Interface {
+		return reflect.PointerTo(typ).MethodByName(name)
+	}
+	return reflect.Method{}, false
+}
+
+// rangeTypes 返回 range 的键和元素类型
+func rangeTypes(value *typeInfo) (key, elem *typeInfo) {
+	if value == nil || value.keys != nil {
+		return nil, nil
+	}
+	typ := value.typ
+	for typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+	switch typ.Kind() {
+	case reflect.Slice, reflect.Array:
+		return newTypeInfo(reflect.TypeOf(0)), newTypeInfo(typ.Elem())
+	case reflect.Map:
+		return newTypeInfo(typ.Key()), newTypeInfo(typ.Elem())
+	case reflect.Chan:
+		return nil, newTypeInfo(typ.Elem())
+	default:
+		return nil, nil
+	}
+}
+
+// copyVars 复制变量作用域
+func copyVars(vars map[string]*typeInfo) map[string]*typeInfo {
+	scope := make(map[string]*typeInfo, len(vars))
+	for k, v := range vars {
+		scope[k] = v
+	}
+	return scope
+}
@@ -0,0 +1,127 @@
+package template
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// 测试用的视图模型
+type (
+	checkTag struct {
+		Name string
+	}
+	checkPost struct {
+		Title  string
+		Author *struct{ Name string }
+		Tags   []checkTag
+		Extra  map[string]any
+	}
+	checkDetailView struct {
+		Post  checkPost `template:"post"`
+		Title string    `template:"title"`
+	}
+)
+
+// Summary 测试方法调用
+func (p *checkPost) Summary() string {
+	return p.Title
+}
+
+// TestTypeChecker 测试模板字段的静态类型检查
+func TestTypeChecker(t *testing.T) {
+	testDir := t.TempDir()
+	require.NoError(t, createLegacyStructure(testDir))
+
+	pageDir := filepath.Join(testDir, "pages", "posts", "detail")
+	require.NoError(t, os.MkdirAll(pageDir, 0755))
+	pageContent := `{{ define "header" }}<title>{{ .title }}</title>{{ end }}
+{{ define "content" }}<h1>{{ .post.Title }}</h1>
+<p>{{ .post.Titel }}</p>
+{{ range .post.Tags }}<i>{{ .Nmae }}</i>{{ end }}
+{{ with .post.Author }}{{ .Name }}{{ end }}
+{{ .post.Extra.anything }}{{ .constant.site }}
+{{ template "meta" .post }}
+{{ end }}
+{{ define "meta" }}{{ .Summary }}{{ $p := . }}{{ $p.Bad }}{{ end }}`
+	require.NoError(t, os.WriteFile(filepath.Join(pageDir, "detail.tmpl"), []byte(pageContent), 0644))
+
+	errorContent := `<h1>{{ .status }} {{ .message }}</h1><p>{{ .requestID }}</p>{{ .context }}`
+	require.NoError(t, os.WriteFile(filepath.Join(testDir, "errors", "410.tmpl"), []byte(errorContent), 0644))
+
+	engine, err := NewEngine(testDir, DefaultLoadTemplate, nil)
+	require.NoError(t, err)
+	defer engine.Close()
+	require.NoError(t, engine.Init())
+
+	t.Run("ReportsUnknownFields", func(t *testing.T) {
+		checker := NewTypeChecker().Register(KindPage, "posts/detail", checkDetailView{})
+		err := engine.CheckTypes(checker)
+		require.Error(t, err)
+
+		var loadErrs LoadErrors
+		require.True(t, errors.As(err, &loadErrs))
+		require.Len(t, loadErrs, 3)
+
+		fields := map[string]*TypeCheckError{}
+		for _, e := range loadErrs {
+			var checkErr *TypeCheckError
+			require.True(t, errors.As(e, &checkErr))
+			fields[checkErr.Field] = checkErr
+		}
+
+		require.Contains(t, fields, ".post.Titel")
+		assert.Equal(t, filepath.Join(pageDir, "detail.tmpl"), fields[".post.Titel"].File)
+		assert.Equal(t, 3, fields[".post.Titel"].Line)
+		assert.Equal(t, "layout.tmpl:pages/posts/detail", fields[".post.Titel"].Template)
+		assert.Equal(t, "template.checkPost", fields[".post.Titel"].Type)
+
+		require.Contains(t, fields, ".Nmae")
+		assert.Equal(t, 4, fields[".Nmae"].Line)
+
+		require.Contains(t, fields, "$p.Bad")
+		assert.Equal(t, 9, fields["$p.Bad"].Line)
+	})
+
+	t.Run("UnknownRootKey", func(t *testing.T) {
+		type view struct {
+			Title string `template:"title"`
+		}
+		err := NewTypeChecker().Register(KindPage, "posts/detail", view{}).Check(engine.CurrentRender())
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "can't evaluate field .post")
+	})
+
+	t.Run("Valid", func(t *testing.T) {
+		type view struct {
+			Title string `template:"title"`
+		}
+		err := NewTypeChecker().Register(KindSingle, "sample", view{}).Check(engine.CurrentRender())
+		assert.NoError(t, err)
+	})
+
+	t.Run("ErrorPageData", func(t *testing.T) {
+		type view struct{}
+		// 错误页面可以使用引擎补充的 status、message 和 requestID，设置 ContextKey 时还可以使用上下文
+		err := NewTypeChecker(ContextKey("context")).Register(KindError, "410", view{}).Check(engine.CurrentRender())
+		assert.NoError(t, err)
+		err = NewTypeChecker(ContextKey("context")).RegisterView(KindError, "410", view{}).Check(engine.CurrentRender())
+		assert.NoError(t, err)
+
+		err = NewTypeChecker().Register(KindError, "410", view{}).Check(engine.CurrentRender())
+		var checkErr *TypeCheckError
+		require.True(t, errors.As(err, &checkErr))
+		assert.Equal(t, ".context", checkErr.Field)
+	})
+
+	t.Run("GenericView", func(t *testing.T) {
+		err := NewTypeChecker().RegisterView(KindPage, "sample", checkPost{}).Check(engine.CurrentRender())
+		require.Error(t, err)
+		// sample 页面使用 .title，泛型方法的模板数据中只有 .Data
+		assert.Contains(t, err.Error(), ".title")
+	})
+}